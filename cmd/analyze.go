@@ -14,36 +14,26 @@ var analyzeCmd = &cobra.Command{
 	Use:   "analyze [file]",
 	Short: "Analyze an infrastructure diagram or documentation",
 	Long: `Analyze infrastructure diagrams or documentation to provide optimization
-recommendations and best practices. The tool can analyze images (diagrams) or
-text documentation.`,
+recommendations and best practices. The tool can analyze images (diagrams), text
+documentation or PDF documents; findings from PDFs cite the page they come from.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get OpenAI API key from config
-		openAIKey := viper.GetString("openai.api_key")
-		if openAIKey == "" {
-			return fmt.Errorf("OpenAI API key not found in configuration")
-		}
-
-		// Claude is optional - if missing, we'll use OpenAI exclusively
-		claudeKey := viper.GetString("claude.api_key")
-		useClaudeIfAvailable := claudeKey != "" && claudeKey != "your-claude-api-key-here"
-
 		// Get file path
 		filePath := args[0]
 		fileExt := filepath.Ext(filePath)
 
-		// Initialize analyzer based on available API keys
-		var analyzer *image.Analyzer
-		if useClaudeIfAvailable {
-			fmt.Println("Using Claude for analysis")
-			analyzer = image.NewAnalyzerWithClaude(claudeKey)
-		} else {
-			fmt.Println("Using OpenAI for analysis")
-			analyzer = image.NewAnalyzer(openAIKey)
+		// Terraform is parsed and checked natively; only a compact summary is sent to the model
+		if fileExt == ".tf" || fileExt == ".hcl" {
+			fmt.Println("Analyzing Terraform source...")
+			return analyzeTerraformSource(filePath)
+		}
+
+		analyzer, useClaudeIfAvailable, err := newAnalyzer()
+		if err != nil {
+			return err
 		}
 
 		var analysis string
-		var err error
 
 		// Analyze based on file type
 		switch fileExt {
@@ -51,14 +41,17 @@ text documentation.`,
 			fmt.Println("Analyzing infrastructure diagram...")
 			if useClaudeIfAvailable {
 				fmt.Println("Note: Image analysis is only supported with OpenAI. Switching to OpenAI for this operation.")
-				tempAnalyzer := image.NewAnalyzer(openAIKey)
+				tempAnalyzer := image.NewAnalyzer(viper.GetString("openai.api_key"))
 				analysis, err = tempAnalyzer.AnalyzeImage(filePath)
 			} else {
 				analysis, err = analyzer.AnalyzeImage(filePath)
 			}
-		case ".txt", ".md", ".yaml", ".yml", ".json", ".tf", ".hcl":
+		case ".txt", ".md", ".yaml", ".yml", ".json":
 			fmt.Println("Analyzing documentation...")
 			analysis, err = analyzer.AnalyzeDocument(filePath)
+		case ".pdf":
+			fmt.Println("Analyzing PDF document...")
+			analysis, err = analyzer.AnalyzePDF(filePath)
 		default:
 			return fmt.Errorf("unsupported file type: %s", fileExt)
 		}
@@ -74,6 +67,28 @@ text documentation.`,
 	},
 }
 
+// newAnalyzer initializes an analyzer based on the API keys available in the configuration.
+// It also reports whether Claude was selected.
+func newAnalyzer() (*image.Analyzer, bool, error) {
+	// Get OpenAI API key from config
+	openAIKey := viper.GetString("openai.api_key")
+	if openAIKey == "" {
+		return nil, false, fmt.Errorf("OpenAI API key not found in configuration")
+	}
+
+	// Claude is optional - if missing, we'll use OpenAI exclusively
+	claudeKey := viper.GetString("claude.api_key")
+	useClaudeIfAvailable := claudeKey != "" && claudeKey != "your-claude-api-key-here"
+
+	if useClaudeIfAvailable {
+		fmt.Println("Using Claude for analysis")
+		return image.NewAnalyzerWithClaude(claudeKey), true, nil
+	}
+
+	fmt.Println("Using OpenAI for analysis")
+	return image.NewAnalyzer(openAIKey), false, nil
+}
+
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
 }
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"cloudigest/pkg/terraform"
+
+	"github.com/spf13/cobra"
+)
+
+var terraformFindingsOnly bool
+
+var analyzeTerraformCmd = &cobra.Command{
+	Use:   "terraform [plan-or-state.json | dir | file.tf]",
+	Short: "Analyze Terraform source, a plan or state",
+	Long: `Analyze Terraform source files (.tf/.hcl, or a module directory) or the JSON output
+of terraform show for a saved plan or the current state. Resources are checked for
+public S3 buckets, security groups open to 0.0.0.0/0, unencrypted storage, missing
+tags and oversized instance types; source files are also checked for undocumented
+variables and unpinned modules. The results are summarized with recommendations.
+Findings reference resource addresses and, for source files, file:line.
+
+Example:
+  cloudigest analyze terraform ./infra
+
+
+  terraform plan -out=tfplan && terraform show -json tfplan > plan.json
+  cloudigest analyze terraform plan.json
+
+  terraform show -json > state.json
+  cloudigest analyze terraform state.json --findings`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		if filepath.Ext(path) == ".json" {
+			return analyzeTerraformJSON(path)
+		}
+		return analyzeTerraformSource(path)
+	},
+}
+
+func analyzeTerraformJSON(path string) error {
+	doc, err := terraform.LoadJSON(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Loaded Terraform %s with %d resource(s)\n", doc.Kind, len(doc.Resources))
+	findings := terraform.Check(doc.Resources)
+	printTerraformFindings(findings)
+
+	if terraformFindingsOnly {
+		return nil
+	}
+
+	summary := terraform.Summarize(doc.Resources, findings)
+	summary["kind"] = doc.Kind
+	summary["terraformVersion"] = doc.TerraformVersion
+	if doc.Kind == "plan" {
+		summary["plannedActions"] = doc.ActionCounts()
+	}
+
+	return summarizeTerraform(summary)
+}
+
+// analyzeTerraformSource parses .tf/.hcl files natively instead of sending raw text to the model
+func analyzeTerraformSource(path string) error {
+	cfg, err := terraform.ParseSource(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Parsed %d file(s): %d resource(s), %d module(s), %d variable(s)\n",
+		len(cfg.Files), len(cfg.Resources), len(cfg.Modules), len(cfg.Variables))
+	findings := terraform.CheckConfig(cfg)
+	printTerraformFindings(findings)
+
+	if terraformFindingsOnly {
+		return nil
+	}
+
+	return summarizeTerraform(terraform.SummarizeConfig(cfg, findings))
+}
+
+func summarizeTerraform(summary map[string]interface{}) error {
+	analyzer, _, err := newAnalyzer()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Summarizing Terraform analysis...")
+	analysis, err := analyzer.AnalyzeTerraform(summary)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nAnalysis Results:")
+	fmt.Println("================")
+	fmt.Println(analysis)
+	return nil
+}
+
+func printTerraformFindings(findings []terraform.Finding) {
+	fmt.Println("\nTerraform Findings:")
+	fmt.Println("===================")
+	if len(findings) == 0 {
+		fmt.Println("No issues found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tRULE\tADDRESS\tLOCATION\tFINDING")
+	for _, f := range findings {
+		location := f.Location
+		if location == "" {
+			location = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Severity, f.Rule, f.Address, location, f.Message)
+	}
+	w.Flush()
+}
+
+func init() {
+	analyzeTerraformCmd.Flags().BoolVar(&terraformFindingsOnly, "findings", false, "only print the deterministic findings without model analysis")
+	analyzeCmd.AddCommand(analyzeTerraformCmd)
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	chatScan   bool
+	chatFilter filterFlags
+)
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Chat with the knowledge base",
+	Long: `Start an interactive conversation over the knowledge base. Unlike query, chat keeps
+the conversation history: follow-up questions are rewritten into standalone queries
+before retrieval, and answered with the previous exchanges in view.
+
+With --scan, the latest results of scan, scan cloud and scan vm are given to the model
+as background, so you can ask about your own infrastructure. The same source filters
+as query can be used.
+
+Commands:
+  /sources       list the sources of the last answer
+  /reset         forget the conversation history
+  /save [file]   save the conversation as Markdown
+  /help          show the commands
+  /exit          leave the chat (or press Ctrl-D)
+
+Example:
+  cloudigest chat
+  cloudigest chat --scan --type runbook`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ragSystem, err := newRAG()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Loading knowledge base...")
+		if err := loadKnowledgeBase(os.Stdout, ragSystem, false); err != nil {
+			return fmt.Errorf("failed to load knowledge base: %v", err)
+		}
+		if err := applyFilter(ragSystem, chatFilter); err != nil {
+			return err
+		}
+
+		var background string
+		if chatScan {
+			var scans []string
+			background, scans, err = loadScanResults()
+			if err != nil {
+				return err
+			}
+			if len(scans) == 0 {
+				fmt.Println("Warning: No saved scan results found. Run cloudigest scan first.")
+			} else {
+				fmt.Printf("Loaded scan results: %s\n", strings.Join(scans, ", "))
+			}
+		}
+
+		conversation := ragSystem.NewConversation(background)
+		fmt.Println("\nAsk a question, or type /help for commands.")
+
+		input := bufio.NewScanner(os.Stdin)
+		input.Buffer(make([]byte, 64*1024), 1024*1024)
+		for {
+			fmt.Print("\n> ")
+			if !input.Scan() {
+				fmt.Println()
+				return input.Err()
+			}
+
+			line := strings.TrimSpace(input.Text())
+			if line == "" {
+				continue
+			}
+			if strings.HasPrefix(line, "/") {
+				if quit := chatCommand(conversation, line); quit {
+					return nil
+				}
+				continue
+			}
+
+			turn, err := conversation.Ask(line)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			if turn.Query != turn.Question {
+				fmt.Printf("(searched for: %s)\n", turn.Query)
+			}
+			fmt.Println()
+			fmt.Println(turn.Answer)
+			if len(turn.References) > 0 {
+				fmt.Printf("\n%d source(s), /sources to list them\n", len(turn.References))
+			}
+		}
+	},
+}
+
+// chatCommand runs a slash command and reports whether the chat should end
+func chatCommand(conversation *rag.Conversation, line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "/exit", "/quit":
+		return true
+	case "/help":
+		fmt.Println("/sources       list the sources of the last answer")
+		fmt.Println("/reset         forget the conversation history")
+		fmt.Println("/save [file]   save the conversation as Markdown")
+		fmt.Println("/exit          leave the chat")
+	case "/sources":
+		if len(conversation.Turns) == 0 {
+			fmt.Println("Nothing has been asked yet.")
+			break
+		}
+		last := conversation.Turns[len(conversation.Turns)-1]
+		if len(last.References) == 0 {
+			fmt.Println("The last answer didn't use any knowledge base sources.")
+			break
+		}
+		printReferences(last.References)
+	case "/reset":
+		conversation.Reset()
+		fmt.Println("Conversation history cleared.")
+	case "/save":
+		path := "cloudigest-chat-" + time.Now().Format("20060102-150405") + ".md"
+		if len(fields) > 1 {
+			path = strings.Join(fields[1:], " ")
+		}
+		if err := os.WriteFile(path, []byte(chatTranscript(conversation)), 0644); err != nil {
+			fmt.Printf("Error: failed to save conversation: %v\n", err)
+			break
+		}
+		fmt.Printf("Saved conversation to %s\n", path)
+	default:
+		fmt.Printf("Unknown command %s, type /help for commands\n", fields[0])
+	}
+	return false
+}
+
+// chatTranscript renders the conversation as Markdown, with the sources of each answer
+func chatTranscript(conversation *rag.Conversation) string {
+	var b strings.Builder
+	b.WriteString("# Cloudigest chat, " + time.Now().Format("2006-01-02 15:04") + "\n")
+	for _, turn := range conversation.Turns {
+		b.WriteString("\n## " + turn.Question + "\n\n")
+		b.WriteString(strings.TrimSpace(turn.Answer) + "\n")
+		if len(turn.References) == 0 {
+			continue
+		}
+
+		b.WriteString("\nSources:\n\n")
+		for i, result := range turn.References {
+			fmt.Fprintf(&b, "%d. %s", i+1, referenceLabel(result))
+			if result.Location != "" && !strings.HasPrefix(result.Location, "builtin:") {
+				fmt.Fprintf(&b, " (%s)", result.Location)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// loadScanResults reads the saved result of each kind of scan, newest first, and
+// returns them joined together with their names
+func loadScanResults() (string, []string, error) {
+	dir, err := scanResultsDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list scan results: %v", err)
+	}
+
+	type scan struct {
+		name    string
+		content []byte
+		modTime time.Time
+	}
+	var scans []scan
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Warning: failed to read scan results %s: %v\n", path, err)
+			continue
+		}
+		scans = append(scans, scan{strings.TrimSuffix(filepath.Base(path), ".md"), content, info.ModTime()})
+	}
+	sort.Slice(scans, func(i, j int) bool { return scans[i].modTime.After(scans[j].modTime) })
+
+	var background strings.Builder
+	var names []string
+	for _, s := range scans {
+		names = append(names, s.name)
+		background.Write(s.content)
+		background.WriteString("\n")
+	}
+	return background.String(), names, nil
+}
+
+func init() {
+	chatCmd.Flags().BoolVar(&chatScan, "scan", false, "give the model the latest scan results as background")
+	chatFilter.register(chatCmd)
+	rootCmd.AddCommand(chatCmd)
+}
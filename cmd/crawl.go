@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudigest/pkg/rag"
+)
+
+// loadCrawlSource crawls a configured documentation site when it is due and returns the
+// locations of all its pages together with the documents to index. Between crawls, and
+// for pages that fail to fetch, the pages that are already indexed are kept.
+func loadCrawlSource(w io.Writer, r *rag.RAG, source sourceConfig, refresh bool, interval time.Duration) ([]string, []rag.Document) {
+	docType := source.Type
+	if docType == "" {
+		docType = "documentation"
+	}
+
+	// Pages of earlier crawls, and when the crawl last ran
+	indexed := make(map[string]rag.SourceRecord)
+	var lastCrawl time.Time
+	current := true
+	for _, record := range r.Sources() {
+		if record.Origin != rag.OriginConfig || !rag.IsURL(record.Location) || !rag.InCrawlScope(source.Crawl, record.Location) {
+			continue
+		}
+		indexed[record.Location] = record
+		if record.CheckedAt.After(lastCrawl) {
+			lastCrawl = record.CheckedAt
+		}
+		current = current && r.IsIndexed(record.Location)
+		r.Relabel(rag.Document{
+			Source:   record.Name,
+			Type:     docType,
+			Location: record.Location,
+			Tags:     source.Tags,
+			Provider: source.Provider,
+		})
+	}
+
+	var locations []string
+	for location := range indexed {
+		locations = append(locations, location)
+	}
+	due := refresh || len(indexed) == 0 || !current || (interval > 0 && time.Since(lastCrawl) >= interval)
+	if !due {
+		return locations, nil
+	}
+
+	fmt.Fprintf(w, "Crawling %s...\n", source.Crawl)
+	result, err := rag.Crawl(rag.CrawlOptions{
+		Start:    source.Crawl,
+		Sitemap:  source.Sitemap,
+		MaxDepth: source.MaxDepth,
+		MaxPages: source.MaxPages,
+		Include:  source.Include,
+		Exclude:  source.Exclude,
+		MaxSize:  int64(maxSourceSize()),
+		Progress: func(pages int) { fmt.Fprintf(w, "\rFetched pages: %d", pages) },
+	}, func(location string) ([]byte, string, error) {
+		fetched, err := fetchSource(location, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		return fetched.content, fetched.contentType, nil
+	})
+	if err != nil {
+		fmt.Fprintf(w, "Warning: failed to crawl %s: %v\n", source.Crawl, err)
+		return locations, nil
+	}
+	if len(result.Pages) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	var notes []string
+	if len(result.Failed) > 0 {
+		notes = append(notes, fmt.Sprintf("%d failed", len(result.Failed)))
+	}
+	if result.Disallowed > 0 {
+		notes = append(notes, fmt.Sprintf("%d disallowed by robots.txt", result.Disallowed))
+	}
+	if result.Truncated {
+		notes = append(notes, "stopped at max_pages")
+	}
+	summary := fmt.Sprintf("Crawled %d page(s) from %s", len(result.Pages), source.Crawl)
+	if len(notes) > 0 {
+		summary += " (" + strings.Join(notes, ", ") + ")"
+	}
+	fmt.Fprintln(w, summary)
+
+	// Nothing came back, e.g. the site is down, so keep what is indexed
+	if len(result.Pages) == 0 {
+		return locations, nil
+	}
+
+	locations = locations[:0]
+	for location := range result.Failed {
+		if _, ok := indexed[location]; ok {
+			locations = append(locations, location)
+		}
+	}
+
+	var docs []rag.Document
+	for _, page := range result.Pages {
+		locations = append(locations, page.URL)
+
+		content, metadata, err := rag.Extract(page.Content, page.ContentType, page.URL)
+		if err != nil {
+			fmt.Fprintf(w, "Warning: Failed to extract text from %s: %v\n", page.URL, err)
+			continue
+		}
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		metadata["url"] = page.URL
+		metadata["crawl"] = source.Crawl
+		metadata["depth"] = strconv.Itoa(page.Depth)
+
+		name := metadata["title"]
+		if name == "" {
+			name = sourceName(page.URL)
+		}
+		if source.Name != "" {
+			name = source.Name + ": " + name
+		}
+
+		doc := rag.Document{
+			Content:  content,
+			Source:   name,
+			Type:     docType,
+			Location: page.URL,
+			Tags:     source.Tags,
+			Provider: source.Provider,
+			Origin:   rag.OriginConfig,
+			Metadata: metadata,
+		}
+		r.Relabel(doc)
+		docs = append(docs, doc)
+	}
+	return locations, docs
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/viper"
+)
+
+// Defaults for fetching sources, overridden by rag.fetch.timeout and rag.fetch.max_size
+const (
+	defaultFetchTimeout  = 30 * time.Second
+	defaultMaxSourceSize = 20 << 20
+)
+
+// fetchedSource is the content of a source with, for URLs, its Content-Type and validators
+type fetchedSource struct {
+	content      []byte
+	contentType  string
+	etag         string
+	lastModified string
+	notModified  bool // the server answered a conditional request with 304 Not Modified
+}
+
+// fetchDocument fetches a source and extracts its text, printing a warning if that fails
+func fetchDocument(w io.Writer, doc rag.Document) (rag.Document, bool) {
+	doc, _, ok := fetchChangedDocument(w, doc, nil)
+	return doc, ok
+}
+
+// refetchDocument fetches an indexed URL source only if it changed, using the ETag and
+// Last-Modified validators saved when it was indexed. A page that wasn't modified is
+// marked as checked and not returned.
+func refetchDocument(w io.Writer, r *rag.RAG, doc rag.Document) (rag.Document, bool) {
+	record, ok := r.Source(doc.Location)
+	if !ok || !r.IsIndexed(doc.Location) || (record.ETag == "" && record.LastModified == "") {
+		return fetchDocument(w, doc)
+	}
+
+	doc, notModified, ok := fetchChangedDocument(w, doc, &record)
+	if notModified {
+		fmt.Fprintf(w, "%s is not modified\n", doc.Location)
+		r.MarkChecked(doc.Location)
+		return doc, false
+	}
+	return doc, ok
+}
+
+// fetchChangedDocument fetches a source, conditionally if a record of it is given, and
+// extracts its text. Failures are printed as warnings.
+func fetchChangedDocument(w io.Writer, doc rag.Document, cached *rag.SourceRecord) (rag.Document, bool, bool) {
+	fmt.Fprintf(w, "Loading document from %s...\n", doc.Location)
+
+	// Record the modification time of local files so unchanged files aren't read again
+	if !rag.IsURL(doc.Location) && doc.ModTime.IsZero() {
+		if info, err := os.Stat(doc.Location); err == nil {
+			doc.ModTime = info.ModTime()
+		}
+	}
+
+	fetched, err := fetchSource(doc.Location, cached)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: %v\n", err)
+		return doc, false, false
+	}
+	if fetched.notModified {
+		return doc, true, false
+	}
+	doc.ETag, doc.LastModified = fetched.etag, fetched.lastModified
+
+	// Strip markup so only the readable text is embedded
+	doc.Content, doc.Metadata, err = rag.Extract(fetched.content, fetched.contentType, doc.Location)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: Failed to extract text from %s: %v\n", doc.Location, err)
+		return doc, false, false
+	}
+	return doc, false, true
+}
+
+// fetchSource reads a source from a URL or a local file. URLs are requested with the
+// validators of the cached record, if any, and must answer within rag.fetch.timeout;
+// sources larger than rag.fetch.max_size are rejected.
+func fetchSource(location string, cached *rag.SourceRecord) (*fetchedSource, error) {
+	maxSize := maxSourceSize()
+
+	if !rag.IsURL(location) {
+		info, err := os.Stat(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document from %s: %v", location, err)
+		}
+		if info.Size() > int64(maxSize) {
+			return nil, fmt.Errorf("%s is larger than the %s limit (rag.fetch.max_size)", location, formatSize(maxSize))
+		}
+		content, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document from %s: %v", location, err)
+		}
+		return &fetchedSource{content: content}, nil
+	}
+
+	timeout := viper.GetDuration("rag.fetch.timeout")
+	if timeout <= 0 {
+		timeout = defaultFetchTimeout
+	}
+
+	req, err := http.NewRequest(http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document from %s: %v", location, err)
+	}
+	req.Header.Set("User-Agent", "cloudigest")
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document from %s: %v", location, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return &fetchedSource{notModified: true}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch document from %s: %s", location, resp.Status)
+	}
+	if resp.ContentLength > int64(maxSize) {
+		return nil, fmt.Errorf("%s is larger than the %s limit (rag.fetch.max_size)", location, formatSize(maxSize))
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document from %s: %v", location, err)
+	}
+	if len(content) > int(maxSize) {
+		return nil, fmt.Errorf("%s is larger than the %s limit (rag.fetch.max_size)", location, formatSize(maxSize))
+	}
+
+	return &fetchedSource{
+		content:      content,
+		contentType:  resp.Header.Get("Content-Type"),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// parseInterval parses a refresh interval: a Go duration such as 12h, or a number of
+// days such as 7d. An empty interval is zero.
+func parseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+
+	interval, err := time.ParseDuration(s)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+	return interval, nil
+}
+
+// maxSourceSize is the size limit for fetched sources, from rag.fetch.max_size
+func maxSourceSize() uint {
+	if maxSize := viper.GetSizeInBytes("rag.fetch.max_size"); maxSize > 0 {
+		return maxSize
+	}
+	return defaultMaxSourceSize
+}
+
+// formatSize prints a byte count in the largest whole unit
+func formatSize(n uint) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", n>>30)
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
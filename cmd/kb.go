@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var kbCmd = &cobra.Command{
+	Use:   "kb",
+	Short: "Manage the knowledge base used by query",
+	Long: `Curate the sources in the knowledge base, inspect what is indexed and check what
+retrieval returns for a question without generating an answer.
+
+Sources listed under rag.sources in the configuration are synced automatically by
+query and kb refresh. Sources added with kb add or kb import are kept until removed
+with kb remove. kb export writes the knowledge base to a bundle that others can
+import without embedding it again.`,
+}
+
+// openKnowledgeBase opens the persistent index for commands that embed but don't
+// generate answers, so they only need the embedding provider to be usable
+func openKnowledgeBase() (*rag.RAG, string, error) {
+	indexDir, err := knowledgeBaseDir()
+	if err != nil {
+		return nil, "", err
+	}
+
+	embedder, err := newEmbedder()
+	if err != nil {
+		return nil, "", err
+	}
+
+	r := rag.NewRAG(viper.GetString("openai.api_key"))
+	r.SetEmbedder(embedder)
+	if err := configureRAG(r); err != nil {
+		return nil, "", err
+	}
+	if err := r.Open(indexDir); err != nil {
+		return nil, "", err
+	}
+
+	return r, indexDir, nil
+}
+
+func init() {
+	rootCmd.AddCommand(kbCmd)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	kbAddName     string
+	kbAddType     string
+	kbAddTags     []string
+	kbAddProvider string
+)
+
+var kbAddCmd = &cobra.Command{
+	Use:   "add <url|path>",
+	Short: "Add a URL or local file to the knowledge base",
+	Long: `Fetch a URL or read a local file, embed it and add it to the knowledge base.
+Adding a source that is already indexed refreshes it.
+
+Example:
+  cloudigest kb add https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+  cloudigest kb add ./runbooks/postgres.md --name "Postgres runbook" --type runbook --tag postgres,internal`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		location := args[0]
+		if !rag.IsURL(location) {
+			abs, err := filepath.Abs(location)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %v", location, err)
+			}
+			info, err := os.Stat(abs)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", location, err)
+			}
+			if info.IsDir() {
+				return fmt.Errorf("%s is a directory", location)
+			}
+			location = abs
+		}
+
+		name := kbAddName
+		if name == "" {
+			name = sourceName(location)
+		}
+
+		r, _, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+
+		if !indexSource(os.Stdout, r, rag.Document{
+			Source:   name,
+			Type:     kbAddType,
+			Tags:     kbAddTags,
+			Provider: kbAddProvider,
+			Location: location,
+			Origin:   rag.OriginManual,
+		}) {
+			return fmt.Errorf("failed to add %s", location)
+		}
+
+		// Bundled examples only stand in for an empty knowledge base
+		r.PruneSources(rag.OriginBuiltin, nil)
+
+		if err := r.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Added %s\n", name)
+		return nil
+	},
+}
+
+// sourceName derives a display name from the last element of a URL or path
+func sourceName(location string) string {
+	if !rag.IsURL(location) {
+		return filepath.Base(location)
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	if base := path.Base(strings.TrimSuffix(u.Path, "/")); base != "." && base != "/" && base != "" {
+		return base
+	}
+	return u.Host
+}
+
+func init() {
+	kbAddCmd.Flags().StringVar(&kbAddName, "name", "", "display name for the source (default is the file or page name)")
+	kbAddCmd.Flags().StringVar(&kbAddType, "type", "documentation", "type of the source, e.g. article or documentation")
+	kbAddCmd.Flags().StringSliceVar(&kbAddTags, "tag", nil, "tags to filter on with query --tag")
+	kbAddCmd.Flags().StringVar(&kbAddProvider, "provider", "", "provider the source is about, e.g. aws, gcp or k8s (inferred from well-known URLs)")
+	kbCmd.AddCommand(kbAddCmd)
+}
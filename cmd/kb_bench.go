@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	kbBenchQueries    int
+	kbBenchK          int
+	kbBenchSynthetic  int
+	kbBenchDimensions int
+)
+
+var kbBenchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Compare approximate and exhaustive vector search",
+	Long: `Build an HNSW graph over the vectors in the knowledge base and compare its search
+latency and recall with an exhaustive scan. Queries are the vectors of random chunks
+with noise added, so no embedding requests are made.
+
+Use --synthetic to benchmark generated vectors instead, for example to see how search
+would scale once much more documentation is ingested. rag.ann.ef_search sets how
+many candidates the graph search examines.
+
+Example:
+  cloudigest kb bench
+  cloudigest kb bench --synthetic 50000 --dimensions 1536`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var vectors [][]float32
+		if kbBenchSynthetic > 0 {
+			fmt.Printf("Generating %d synthetic %d-dimensional vectors...\n", kbBenchSynthetic, kbBenchDimensions)
+			vectors = rag.SyntheticVectors(kbBenchSynthetic, kbBenchDimensions, 1)
+		} else {
+			r, _, err := openKnowledgeBase()
+			if err != nil {
+				return err
+			}
+			vectors = r.Vectors()
+		}
+		if len(vectors) == 0 {
+			return fmt.Errorf("the knowledge base is empty; run kb refresh first or use --synthetic")
+		}
+
+		efSearch := viper.GetInt("rag.ann.ef_search")
+		if efSearch <= 0 {
+			efSearch = 100
+		}
+
+		fmt.Println("Building graph and running queries...")
+		result := rag.BenchmarkSearch(vectors, rag.PerturbedQueries(vectors, kbBenchQueries, 2), kbBenchK, efSearch)
+
+		fmt.Println("\nVector Search Benchmark:")
+		fmt.Println("========================")
+		fmt.Printf("Vectors:        %d x %d dimensions\n", result.Vectors, result.Dimensions)
+		fmt.Printf("Queries:        %d, top %d, ef_search %d\n", result.Queries, result.K, result.EfSearch)
+		fmt.Printf("Graph build:    %v\n", result.BuildTime.Round(time.Millisecond))
+		fmt.Printf("Exhaustive:     %v per query\n", result.BruteForce)
+		fmt.Printf("HNSW:           %v per query\n", result.Graph)
+		if result.Graph > 0 {
+			fmt.Printf("Speedup:        %.1fx\n", float64(result.BruteForce)/float64(result.Graph))
+		}
+		fmt.Printf("%-16s%.1f%%\n", fmt.Sprintf("Recall@%d:", result.K), result.Recall*100)
+		return nil
+	},
+}
+
+func init() {
+	kbBenchCmd.Flags().IntVar(&kbBenchQueries, "queries", 100, "number of queries to run")
+	kbBenchCmd.Flags().IntVar(&kbBenchK, "k", 10, "number of nearest neighbours per query")
+	kbBenchCmd.Flags().IntVar(&kbBenchSynthetic, "synthetic", 0, "benchmark this many generated vectors instead of the knowledge base")
+	kbBenchCmd.Flags().IntVar(&kbBenchDimensions, "dimensions", 1536, "dimensions of the generated vectors")
+	kbCmd.AddCommand(kbBenchCmd)
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	kbEvalK        int
+	kbEvalGenerate bool
+	kbEvalJSON     bool
+)
+
+// evalQuestion is an entry of an evaluation file
+type evalQuestion struct {
+	Question       string   `mapstructure:"question"`
+	Sources        []string `mapstructure:"sources"`
+	Keywords       []string `mapstructure:"keywords"`
+	AnswerKeywords []string `mapstructure:"answer_keywords"`
+}
+
+var kbEvalCmd = &cobra.Command{
+	Use:   "eval <file>",
+	Short: "Measure retrieval quality on a set of questions",
+	Long: `Run retrieval for every question in a YAML or JSON file and score the retrieved
+chunks against what each question expects, to compare chunking, embedding and
+retrieval settings with data rather than by eye.
+
+Each question lists the sources that answer it (substrings of source names or
+locations) and/or keywords the retrieved chunks should contain:
+
+  questions:
+    - question: "How should I set pod resource limits?"
+      sources: ["manage-resources-containers"]
+      keywords: ["requests", "limits"]
+      answer_keywords: ["requests"]   # checked with --generate; defaults to keywords
+
+Reported metrics, over the top k chunks (--k, default rag.top_k):
+  Recall@k         expected sources and keywords found in the retrieved chunks
+  MRR              mean of 1/rank of the first relevant chunk, i.e. one from an expected
+                   source or, without sources, one containing a keyword
+  Hit rate         questions with a relevant chunk in the top k
+  Answer coverage  answer keywords found in the generated answer (with --generate)
+
+Run it again after changing rag.chunk_size, the embedding model or other settings;
+the knowledge base is re-indexed first when the chunking changed.
+
+Example:
+  cloudigest kb eval eval.yaml
+  cloudigest kb eval eval.yaml --k 5 --generate
+  cloudigest kb eval eval.yaml --json > before.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cases, err := loadEvalFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		var r *rag.RAG
+		if kbEvalGenerate {
+			r, err = newRAG()
+		} else {
+			r, _, err = openKnowledgeBase()
+		}
+		if err != nil {
+			return err
+		}
+
+		// Progress goes to stderr so that --json output stays clean
+		var progress io.Writer = os.Stdout
+		if kbEvalJSON {
+			progress = os.Stderr
+		}
+		fmt.Fprintln(progress, "Loading knowledge base...")
+		if err := loadKnowledgeBase(progress, r, false); err != nil {
+			return fmt.Errorf("failed to load knowledge base: %v", err)
+		}
+		if kbEvalK > 0 {
+			r.SetTopK(kbEvalK)
+		}
+
+		results := make([]rag.EvalResult, len(cases))
+		for i, c := range cases {
+			if !kbEvalJSON {
+				fmt.Printf("\rEvaluating question %d/%d", i+1, len(cases))
+			}
+			results[i] = r.Evaluate(c, kbEvalGenerate)
+		}
+		summary := rag.SummarizeEval(results)
+
+		if kbEvalJSON {
+			return printEvalJSON(r, results, summary)
+		}
+		fmt.Println()
+		printEvalReport(r, results, summary)
+		return nil
+	},
+}
+
+// loadEvalFile reads the questions of an evaluation file
+func loadEvalFile(path string) ([]rag.EvalCase, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read evaluation file: %v", err)
+	}
+
+	var questions []evalQuestion
+	if err := v.UnmarshalKey("questions", &questions); err != nil {
+		return nil, fmt.Errorf("failed to read questions from %s: %v", path, err)
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("%s has no questions", path)
+	}
+
+	cases := make([]rag.EvalCase, len(questions))
+	for i, q := range questions {
+		if strings.TrimSpace(q.Question) == "" {
+			return nil, fmt.Errorf("question %d in %s is empty", i+1, path)
+		}
+		if len(q.Sources) == 0 && len(q.Keywords) == 0 {
+			return nil, fmt.Errorf("question %d in %s has no expected sources or keywords", i+1, path)
+		}
+		cases[i] = rag.EvalCase{
+			Question:       q.Question,
+			Sources:        q.Sources,
+			Keywords:       q.Keywords,
+			AnswerKeywords: q.AnswerKeywords,
+		}
+	}
+	return cases, nil
+}
+
+func printEvalReport(r *rag.RAG, results []rag.EvalResult, summary rag.EvalSummary) {
+	settings := r.Settings()
+
+	fmt.Println("\nQuestions:")
+	fmt.Println("==========")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if summary.Answered > 0 {
+		fmt.Fprintln(w, "#\tRANK\tRECALL\tANSWER\tQUESTION")
+	} else {
+		fmt.Fprintln(w, "#\tRANK\tRECALL\tQUESTION")
+	}
+	for i, result := range results {
+		question := snippet(result.Case.Question, 60)
+		if result.Err != nil {
+			fmt.Fprintf(w, "%d\t-\t-\t%s\n", i+1, question)
+			continue
+		}
+		rank := "-"
+		if result.Rank > 0 {
+			rank = fmt.Sprint(result.Rank)
+		}
+		if summary.Answered > 0 {
+			fmt.Fprintf(w, "%d\t%s\t%.0f%%\t%.0f%%\t%s\n", i+1, rank, result.Recall()*100, result.AnswerCoverage()*100, question)
+		} else {
+			fmt.Fprintf(w, "%d\t%s\t%.0f%%\t%s\n", i+1, rank, result.Recall()*100, question)
+		}
+	}
+	w.Flush()
+
+	// What each question missed is what to look at when tuning
+	var misses []string
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+			misses = append(misses, fmt.Sprintf("%d. error: %v", i+1, result.Err))
+		case len(result.Missing) > 0 || len(result.AnswerMissing) > 0:
+			missing := result.Missing
+			for _, keyword := range result.AnswerMissing {
+				missing = append(missing, "answer keyword "+keyword)
+			}
+			misses = append(misses, fmt.Sprintf("%d. missing %s", i+1, strings.Join(missing, ", ")))
+		}
+	}
+	if len(misses) > 0 {
+		fmt.Println("\nMisses:")
+		fmt.Println("=======")
+		for _, miss := range misses {
+			fmt.Println(miss)
+		}
+	}
+
+	fmt.Println("\nRetrieval Evaluation:")
+	fmt.Println("=====================")
+	fmt.Printf("Embedding model:  %s\n", r.EmbeddingModel())
+	fmt.Printf("Settings:         chunk_size %d, chunk_overlap %d, top_k %d, min_score %.2f, keyword_weight %.2f\n",
+		settings.ChunkSize, settings.ChunkOverlap, settings.TopK, settings.MinScore, settings.KeywordWeight)
+	fmt.Printf("Questions:        %d", summary.Questions)
+	if summary.Failed > 0 {
+		fmt.Printf(" (%d failed)", summary.Failed)
+	}
+	fmt.Println()
+	fmt.Printf("%-18s%.1f%%\n", fmt.Sprintf("Recall@%d:", settings.TopK), summary.Recall*100)
+	fmt.Printf("MRR:              %.3f\n", summary.MRR)
+	fmt.Printf("Hit rate:         %.1f%%\n", summary.HitRate*100)
+	if summary.Answered > 0 {
+		fmt.Printf("Answer coverage:  %.1f%% (%d question(s))\n", summary.AnswerCoverage*100, summary.Answered)
+	}
+}
+
+// printEvalJSON prints the settings, summary and per-question scores as JSON, so that
+// runs with different settings can be compared
+func printEvalJSON(r *rag.RAG, results []rag.EvalResult, summary rag.EvalSummary) error {
+	type questionReport struct {
+		Question       string   `json:"question"`
+		Rank           int      `json:"rank"`
+		Recall         float64  `json:"recall"`
+		Missing        []string `json:"missing,omitempty"`
+		AnswerCoverage *float64 `json:"answer_coverage,omitempty"`
+		Sources        []string `json:"retrieved"`
+		Error          string   `json:"error,omitempty"`
+	}
+	settings := r.Settings()
+	report := struct {
+		EmbeddingModel string           `json:"embedding_model"`
+		ChunkSize      int              `json:"chunk_size"`
+		ChunkOverlap   int              `json:"chunk_overlap"`
+		TopK           int              `json:"top_k"`
+		MinScore       float32          `json:"min_score"`
+		KeywordWeight  float64          `json:"keyword_weight"`
+		Questions      int              `json:"questions"`
+		Failed         int              `json:"failed"`
+		Recall         float64          `json:"recall"`
+		MRR            float64          `json:"mrr"`
+		HitRate        float64          `json:"hit_rate"`
+		AnswerCoverage *float64         `json:"answer_coverage,omitempty"`
+		Results        []questionReport `json:"results"`
+	}{
+		EmbeddingModel: r.EmbeddingModel(),
+		ChunkSize:      settings.ChunkSize,
+		ChunkOverlap:   settings.ChunkOverlap,
+		TopK:           settings.TopK,
+		MinScore:       settings.MinScore,
+		KeywordWeight:  settings.KeywordWeight,
+		Questions:      summary.Questions,
+		Failed:         summary.Failed,
+		Recall:         summary.Recall,
+		MRR:            summary.MRR,
+		HitRate:        summary.HitRate,
+	}
+	if summary.Answered > 0 {
+		report.AnswerCoverage = &summary.AnswerCoverage
+	}
+
+	for _, result := range results {
+		q := questionReport{Question: result.Case.Question, Rank: result.Rank, Recall: result.Recall(), Missing: result.Missing}
+		if result.Err != nil {
+			q.Error = result.Err.Error()
+		}
+		if result.Answered {
+			coverage := result.AnswerCoverage()
+			q.AnswerCoverage = &coverage
+		}
+		for _, chunk := range result.Retrieved {
+			q.Sources = append(q.Sources, referenceLabel(chunk))
+		}
+		report.Results = append(report.Results, q)
+	}
+
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode evaluation: %v", err)
+	}
+	fmt.Println(string(encoded))
+	return nil
+}
+
+func init() {
+	kbEvalCmd.Flags().IntVar(&kbEvalK, "k", 0, "number of chunks retrieved per question (default rag.top_k)")
+	kbEvalCmd.Flags().BoolVar(&kbEvalGenerate, "generate", false, "also generate answers and score them against answer_keywords")
+	kbEvalCmd.Flags().BoolVar(&kbEvalJSON, "json", false, "print the results as JSON")
+	kbCmd.AddCommand(kbEvalCmd)
+}
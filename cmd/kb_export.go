@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var kbExportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export the knowledge base to a bundle file",
+	Long: `Write the indexed sources, with their chunks, metadata and vectors, to a versioned
+bundle file that teammates can load with kb import instead of fetching and embedding
+everything again. The bundle records the embedding model, and can only be imported
+into a knowledge base that uses the same model.
+
+Example:
+  cloudigest kb refresh
+  cloudigest kb export team-kb.bundle`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, _, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+
+		info, err := r.Export(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Exported %d sources (%d chunks) to %s\n", info.Sources, info.Chunks, args[0])
+		fmt.Printf("Embedding model: %s (%d dimensions)\n", info.EmbeddingModel, info.Dimensions)
+		if stat, err := os.Stat(args[0]); err == nil {
+			fmt.Printf("Bundle size:     %.1f KiB\n", float64(stat.Size())/1024)
+		}
+		return nil
+	},
+}
+
+func init() {
+	kbCmd.AddCommand(kbExportCmd)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+)
+
+var kbImportReplace bool
+
+var kbImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a bundle exported with kb export",
+	Long: `Add the sources of a bundle written by kb export to the knowledge base, with their
+vectors, so nothing needs to be fetched or embedded. Vectors from different embedding
+models can't be compared, so a bundle is refused unless it was embedded with the model
+this installation is configured to use (rag.embeddings).
+
+Sources that are already indexed are replaced when the bundle has a different version
+of them. With --replace, sources that aren't in the bundle are removed as well.
+Imported sources are kept until removed with kb remove, and imported URLs are
+refetched like sources added with kb add.
+
+Example:
+  cloudigest kb import team-kb.bundle
+  cloudigest kb import team-kb.bundle --replace`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bundle, err := rag.ReadBundle(args[0])
+		if err != nil {
+			return err
+		}
+
+		r, _, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Bundle: %d sources (%d chunks), embedded with %s, exported %s\n", bundle.Info.Sources,
+			bundle.Info.Chunks, bundle.Info.EmbeddingModel, bundle.Info.CreatedAt.Local().Format("2006-01-02 15:04"))
+
+		result, err := r.Import(bundle, kbImportReplace)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %v", args[0], err)
+		}
+
+		// Bundled examples only stand in for an empty knowledge base
+		if result.Added+result.Updated+result.Unchanged > 0 {
+			r.PruneSources(rag.OriginBuiltin, nil)
+		}
+
+		if err := r.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Imported %d chunks: %d sources added, %d updated, %d unchanged", result.Chunks,
+			result.Added, result.Updated, result.Unchanged)
+		if kbImportReplace {
+			fmt.Printf(", %d removed", result.Removed)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+func init() {
+	kbImportCmd.Flags().BoolVar(&kbImportReplace, "replace", false, "remove sources that aren't in the bundle")
+	kbCmd.AddCommand(kbImportCmd)
+}
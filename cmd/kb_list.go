@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var kbListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the sources in the knowledge base",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, _, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+
+		sources := r.Sources()
+		if len(sources) == 0 {
+			fmt.Println("The knowledge base is empty. Add sources with `cloudigest kb add` or rag.sources in the configuration.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tPROVIDER\tTAGS\tORIGIN\tCHUNKS\tUPDATED\tLOCATION")
+		for _, s := range sources {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", s.Name, s.Type, orDash(s.Provider), orDash(strings.Join(s.Tags, ",")),
+				s.Origin, s.Chunks, s.UpdatedAt.Local().Format("2006-01-02 15:04"), s.Location)
+		}
+		return w.Flush()
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	kbCmd.AddCommand(kbListCmd)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var kbRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Refetch every source and re-embed the ones that changed",
+	Long: `Check every source for changes and re-embed the ones whose content changed.
+URLs are requested with the ETag and Last-Modified of the indexed version, so pages
+that weren't modified aren't downloaded again. Pages that fail to fetch, answer with an
+error status, or exceed rag.fetch.max_size keep their indexed version.
+
+To check URLs automatically instead, set rag.refresh_interval or a source's
+refresh_interval; query, chat, kb search and scan --kb then recheck the sources that are due.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, _, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+
+		if err := loadKnowledgeBase(os.Stdout, r, true); err != nil {
+			return fmt.Errorf("failed to refresh knowledge base: %v", err)
+		}
+
+		stats := r.Stats()
+		fmt.Printf("Knowledge base has %d source(s) and %d chunk(s)\n", stats.Sources, stats.Chunks)
+		return nil
+	},
+}
+
+func init() {
+	kbCmd.AddCommand(kbRefreshCmd)
+}
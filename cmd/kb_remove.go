@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+)
+
+var kbRemoveCmd = &cobra.Command{
+	Use:   "remove <name|location>",
+	Short: "Remove a source and its chunks from the knowledge base",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, _, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+
+		var matches []rag.SourceRecord
+		for _, s := range r.Sources() {
+			if s.Location == args[0] {
+				matches = []rag.SourceRecord{s}
+				break
+			}
+			if s.Name == args[0] {
+				matches = append(matches, s)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("no source named %q in the knowledge base", args[0])
+		case 1:
+		default:
+			return fmt.Errorf("%d sources are named %q, remove one by location instead", len(matches), args[0])
+		}
+
+		source := matches[0]
+		r.RemoveSource(source.Location)
+		if err := r.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed %s (%d chunks)\n", source.Name, source.Chunks)
+		if source.Origin == rag.OriginConfig {
+			fmt.Println("Warning: this source is listed under rag.sources and will be added again on the next sync. Remove it from the configuration to drop it for good.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	kbCmd.AddCommand(kbRemoveCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+)
+
+var kbSearchFilter filterFlags
+
+var kbSearchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Show the chunks retrieval returns for a question",
+	Long: `Embed the text and print the chunks that query would pass to the model as
+context, with their scores, without generating an answer. Chunks are ranked by
+fusing cosine similarity with BM25 keyword matching (weighted by rag.keyword_weight);
+the number of chunks and the minimum similarity are set by rag.top_k and rag.min_score.
+Keyword matches need the minimum similarity as well.
+The same source filters as query can be used.
+
+Example:
+  cloudigest kb search "pod resource limits"
+  cloudigest kb search --provider k8s --type documentation "pod resource limits"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, _, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+		if err := loadKnowledgeBase(os.Stdout, r, false); err != nil {
+			return fmt.Errorf("failed to load knowledge base: %v", err)
+		}
+
+		if err := applyFilter(r, kbSearchFilter); err != nil {
+			return err
+		}
+
+		results, err := r.Search(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("\nSearch Results:")
+		fmt.Println("===============")
+		if len(results) == 0 {
+			fmt.Println("No matching chunks.")
+			return nil
+		}
+		for i, result := range results {
+			fmt.Printf("%d. %s (%s)\n", i+1, result.Source, result.Location)
+			if labels := sourceLabels(result.Document); labels != "" {
+				fmt.Printf("   %s\n", labels)
+			}
+			fmt.Printf("   score %.4f, cosine %.3f, bm25 %.2f\n", result.Score, result.Similarity, result.KeywordScore)
+			fmt.Printf("   %s\n\n", snippet(result.Content, 300))
+		}
+		return nil
+	},
+}
+
+// sourceLabels describes the filterable metadata of a source
+func sourceLabels(doc rag.Document) string {
+	var labels []string
+	if doc.Type != "" {
+		labels = append(labels, "type "+doc.Type)
+	}
+	if doc.Provider != "" {
+		labels = append(labels, "provider "+doc.Provider)
+	}
+	if len(doc.Tags) > 0 {
+		labels = append(labels, "tags "+strings.Join(doc.Tags, ", "))
+	}
+	return strings.Join(labels, "; ")
+}
+
+// snippet collapses whitespace and truncates text to at most n characters
+func snippet(text string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n]) + "..."
+}
+
+func init() {
+	kbSearchFilter.register(kbSearchCmd)
+	kbCmd.AddCommand(kbSearchCmd)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"cloudigest/pkg/rag"
+
+	"github.com/spf13/cobra"
+)
+
+var kbStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show knowledge base statistics",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, indexDir, err := openKnowledgeBase()
+		if err != nil {
+			return err
+		}
+
+		stats := r.Stats()
+		origins := make(map[string]int)
+		for _, s := range r.Sources() {
+			origins[s.Origin]++
+		}
+
+		fmt.Println("Knowledge Base:")
+		fmt.Println("===============")
+		fmt.Printf("Index:           %s\n", rag.IndexPath(indexDir))
+		if info, err := os.Stat(rag.IndexPath(indexDir)); err == nil {
+			fmt.Printf("Index size:      %.1f KiB\n", float64(info.Size())/1024)
+		}
+		fmt.Printf("Embedding model: %s\n", stats.EmbeddingModel)
+		fmt.Printf("Dimensions:      %d\n", stats.Dimensions)
+		fmt.Printf("Sources:         %d (%d config, %d manual, %d imported, %d builtin)\n", stats.Sources,
+			origins[rag.OriginConfig], origins[rag.OriginManual], origins[rag.OriginImport], origins[rag.OriginBuiltin])
+		fmt.Printf("Chunks:          %d\n", stats.Chunks)
+		fmt.Printf("Words:           %d\n", stats.Words)
+		fmt.Printf("Vector search:   %s\n", stats.VectorSearch)
+		return nil
+	},
+}
+
+func init() {
+	kbCmd.AddCommand(kbStatsCmd)
+}
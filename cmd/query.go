@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"cloudigest/pkg/rag"
 
@@ -11,6 +14,13 @@ import (
 	"github.com/spf13/viper"
 )
 
+var (
+	queryRefresh     bool
+	queryShowContext bool
+	queryWeb         bool
+	queryFilter      filterFlags
+)
+
 var queryCmd = &cobra.Command{
 	Use:   "query [question]",
 	Short: "Query the knowledge base with a natural language question",
@@ -18,39 +28,45 @@ var queryCmd = &cobra.Command{
 Kubernetes, or any other cloud-related topics. The system will use retrieval-augmented
 generation (RAG) to provide you with relevant, actionable information and best practices.
 
+Embedded sources are kept in a persistent index under rag.index_dir (default
+~/.cloudigest/kb), so only sources that are new since the last run are fetched and
+embedded. Use --refresh to refetch every source; only changed content is re-embedded.
+
+Use --type, --source, --tag, --provider and --since to only retrieve from matching
+sources, e.g. --type runbook to answer from internal runbooks only. Flags can be
+repeated or given comma-separated values; a source must match every kind of filter
+that is set, and any of the values given for it.
+
+Chunks are retrieved by fusing vector similarity with BM25 keyword matching; every
+chunk, keyword matches included, needs a cosine similarity of at least rag.min_score.
+
+The answer cites the retrieved chunks by number, and is followed by a list of the
+references with their source, section and similarity score. Use --show-context to
+also print the retrieved chunks that were sent to the model.
+
+With --web, the question is also searched on the web with Serper (serper.api_key),
+the top serper.results pages (default 3) are fetched, and their most relevant chunks
+are added to the context after the knowledge base chunks. They are cited like other
+sources, with their URLs in the references. Source filters don't apply to web results.
+
 Example:
   cloudigest query "what is the best way to deploy Kubernetes?"
-  cloudigest query "how can I optimize my AWS EC2 costs?"`,
+  cloudigest query "how can I optimize my AWS EC2 costs?"
+  cloudigest query --type runbook --tag postgres "how do I fail over the database?"
+  cloudigest query --web "what is new in the latest EKS release?"`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get OpenAI API key from config
-		openAIKey := viper.GetString("openai.api_key")
-		if openAIKey == "" {
-			return fmt.Errorf("OpenAI API key not found in configuration")
+		ragSystem, err := newRAG()
+		if err != nil {
+			return err
 		}
 
-		// Claude is optional - if missing, we'll use OpenAI exclusively
-		claudeKey := viper.GetString("claude.api_key")
-		useClaudeIfAvailable := claudeKey != "" && claudeKey != "your-claude-api-key-here"
-
-		// Initialize RAG system based on available API keys
-		var ragSystem *rag.RAG
-		if useClaudeIfAvailable {
+		if _, ok := ragClaudeKey(); ok {
 			fmt.Println("Using Claude for query processing")
-			ragSystem = rag.NewRAGWithClaude(claudeKey)
 		} else {
 			fmt.Println("Using OpenAI for query processing")
-			ragSystem = rag.NewRAG(openAIKey)
-		}
-
-		// Configure RAG settings from config
-		if chunkSize := viper.GetInt("rag.chunk_size"); chunkSize > 0 {
-			ragSystem.SetChunkSize(chunkSize)
-		}
-
-		if maxTokens := viper.GetInt("rag.max_tokens"); maxTokens > 0 {
-			ragSystem.SetMaxTokens(maxTokens)
 		}
+		fmt.Printf("Using %s for embeddings\n", ragSystem.EmbeddingModel())
 
 		// Get user's question
 		question := args[0]
@@ -58,77 +74,489 @@ Example:
 
 		// Load knowledge base documents from config
 		fmt.Println("Loading knowledge base...")
-		err := loadKnowledgeBase(ragSystem)
-		if err != nil {
+		if err := loadKnowledgeBase(os.Stdout, ragSystem, queryRefresh); err != nil {
 			return fmt.Errorf("failed to load knowledge base: %v", err)
 		}
 
+		if err := applyFilter(ragSystem, queryFilter); err != nil {
+			return err
+		}
+
+		var webDocs []rag.Document
+		if queryWeb {
+			provider, err := newSearchProvider()
+			if err != nil {
+				return err
+			}
+			if webDocs, err = webDocuments(provider, question); err != nil {
+				return err
+			}
+		}
+
 		// Query the RAG system
 		fmt.Println("Searching knowledge base and generating answer...")
-		answer, err := ragSystem.Query(question)
+		answer, err := ragSystem.QueryWith(question, webDocs)
 		if err != nil {
 			return fmt.Errorf("failed to process query: %v", err)
 		}
 
+		if queryShowContext {
+			printContext(answer.References)
+		}
+
 		// Display results
 		fmt.Println("\nAnswer:")
 		fmt.Println("=======")
-		fmt.Println(answer)
+		fmt.Println(answer.Text)
+
+		printReferences(answer.References)
 		return nil
 	},
 }
 
-// loadKnowledgeBase adds documents to the RAG system
-// It loads document sources from the configuration
-func loadKnowledgeBase(r *rag.RAG) error {
-	// Get sources from config
-	var sources []struct {
-		URL  string `mapstructure:"url"`
-		Type string `mapstructure:"type"`
-		Name string `mapstructure:"name"`
+// filterFlags are the retrieval filters shared by query and kb search
+type filterFlags struct {
+	types     []string
+	sources   []string
+	tags      []string
+	providers []string
+	since     string
+}
+
+func (f *filterFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&f.types, "type", nil, "only use sources of these types, e.g. runbook")
+	cmd.Flags().StringSliceVar(&f.sources, "source", nil, "only use sources whose name or location contains this text")
+	cmd.Flags().StringSliceVar(&f.tags, "tag", nil, "only use sources with any of these tags")
+	cmd.Flags().StringSliceVar(&f.providers, "provider", nil, "only use sources about these providers, e.g. aws, gcp or k8s")
+	cmd.Flags().StringVar(&f.since, "since", "", "only use sources changed on or after this date (YYYY-MM-DD)")
+}
+
+// applyFilter sets the retrieval filter from the flags, warning when no source matches
+func applyFilter(r *rag.RAG, flags filterFlags) error {
+	filter := rag.Filter{
+		Types:     flags.types,
+		Sources:   flags.sources,
+		Tags:      flags.tags,
+		Providers: flags.providers,
+	}
+	if flags.since != "" {
+		since, err := time.ParseInLocation("2006-01-02", flags.since, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --since date %q, expected YYYY-MM-DD", flags.since)
+		}
+		filter.Since = since
+	}
+	if filter.IsZero() {
+		return nil
+	}
+
+	matching := 0
+	for _, record := range r.Sources() {
+		if filter.Match(&record) {
+			matching++
+		}
+	}
+	if matching == 0 {
+		fmt.Println("Warning: No sources in the knowledge base match the filter.")
+	} else {
+		fmt.Printf("Retrieving from %d matching source(s)\n", matching)
+	}
+
+	r.SetFilter(filter)
+	return nil
+}
+
+// printContext prints the numbered chunks that were passed to the model as context
+func printContext(results []rag.SearchResult) {
+	fmt.Println("\nContext:")
+	fmt.Println("========")
+	if len(results) == 0 {
+		fmt.Println("No relevant chunks were found in the knowledge base.")
+		return
+	}
+	for i, result := range results {
+		fmt.Printf("[%d] %s\n", i+1, referenceLabel(result))
+		fmt.Println(strings.TrimSpace(result.Content))
+		fmt.Println()
+	}
+}
+
+// printReferences lists the sources the answer's [n] citations refer to
+func printReferences(results []rag.SearchResult) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Println("\nReferences:")
+	fmt.Println("===========")
+	for i, result := range results {
+		fmt.Printf("[%d] %s (similarity %.3f)\n", i+1, referenceLabel(result), result.Similarity)
+		if result.Location != "" && !strings.HasPrefix(result.Location, "builtin:") {
+			fmt.Printf("    %s\n", result.Location)
+		}
+	}
+}
+
+// referenceLabel names a chunk by its source, section and page
+func referenceLabel(result rag.SearchResult) string {
+	label := result.Source
+	if section := result.Metadata["section"]; section != "" {
+		label += " - " + section
+	}
+	if page := result.Metadata["page"]; page != "" {
+		label += ", page " + page
+	}
+	return label
+}
+
+// newRAG creates the RAG system from the configuration and opens the persistent index.
+// Answers are generated by Claude when a key is configured and by OpenAI otherwise;
+// embeddings come from the provider configured under rag.embeddings.
+func newRAG() (*rag.RAG, error) {
+	embedder, err := newEmbedder()
+	if err != nil {
+		return nil, err
+	}
+
+	// Claude is optional - if missing, we'll use OpenAI exclusively
+	var ragSystem *rag.RAG
+	if claudeKey, ok := ragClaudeKey(); ok {
+		ragSystem = rag.NewRAGWithClaude(claudeKey)
+	} else {
+		// Get OpenAI API key from config
+		openAIKey := viper.GetString("openai.api_key")
+		if openAIKey == "" {
+			return nil, fmt.Errorf("OpenAI API key not found in configuration")
+		}
+		ragSystem = rag.NewRAG(openAIKey)
+	}
+	ragSystem.SetEmbedder(embedder)
+
+	if err := configureRAG(ragSystem); err != nil {
+		return nil, err
+	}
+
+	indexDir, err := knowledgeBaseDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := ragSystem.Open(indexDir); err != nil {
+		return nil, err
+	}
+
+	return ragSystem, nil
+}
+
+// newEmbedder creates the embedding provider configured under rag.embeddings. The
+// openai provider falls back to openai.api_key when no separate key is set.
+func newEmbedder() (rag.Embedder, error) {
+	apiKey := viper.GetString("rag.embeddings.api_key")
+	if apiKey == "" {
+		apiKey = viper.GetString("openai.api_key")
+	}
+
+	embedder, err := rag.NewEmbedder(
+		viper.GetString("rag.embeddings.provider"),
+		viper.GetString("rag.embeddings.model"),
+		viper.GetString("rag.embeddings.base_url"),
+		apiKey,
+		viper.GetInt("rag.embeddings.dimensions"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rag.embeddings configuration: %v", err)
+	}
+	return embedder, nil
+}
+
+// configureRAG applies the rag settings from the configuration
+func configureRAG(r *rag.RAG) error {
+	if chunkSize := viper.GetInt("rag.chunk_size"); chunkSize > 0 {
+		r.SetChunkSize(chunkSize)
+	}
+
+	if overlap := viper.GetInt("rag.chunk_overlap"); overlap > 0 {
+		if overlap >= viper.GetInt("rag.chunk_size") && viper.IsSet("rag.chunk_size") {
+			return fmt.Errorf("rag.chunk_overlap must be smaller than rag.chunk_size")
+		}
+		r.SetChunkOverlap(overlap)
+	}
+
+	if maxTokens := viper.GetInt("rag.max_tokens"); maxTokens > 0 {
+		r.SetMaxTokens(maxTokens)
 	}
 
+	if topK := viper.GetInt("rag.top_k"); topK > 0 {
+		r.SetTopK(topK)
+	}
+
+	if minScore := viper.GetFloat64("rag.min_score"); minScore > 0 {
+		r.SetMinScore(float32(minScore))
+	}
+
+	if batchSize := viper.GetInt("rag.embeddings.batch_size"); batchSize > 0 {
+		r.SetEmbeddingBatchSize(batchSize)
+	}
+
+	if concurrency := viper.GetInt("rag.embeddings.concurrency"); concurrency > 0 {
+		r.SetEmbeddingConcurrency(concurrency)
+	}
+
+	annEnabled := true
+	if viper.IsSet("rag.ann.enabled") {
+		annEnabled = viper.GetBool("rag.ann.enabled")
+	}
+	r.SetANN(annEnabled, viper.GetInt("rag.ann.min_chunks"), viper.GetInt("rag.ann.ef_search"))
+
+	if viper.IsSet("rag.keyword_weight") {
+		r.SetKeywordWeight(viper.GetFloat64("rag.keyword_weight"))
+	}
+
+	for docType, name := range viper.GetStringMapString("rag.chunkers") {
+		if err := r.SetChunker(docType, name); err != nil {
+			return fmt.Errorf("invalid rag.chunkers entry for %s: %v", docType, err)
+		}
+	}
+
+	return nil
+}
+
+// ragClaudeKey returns the Claude API key if one is configured
+func ragClaudeKey() (string, bool) {
+	claudeKey := viper.GetString("claude.api_key")
+	return claudeKey, claudeKey != "" && claudeKey != "your-claude-api-key-here"
+}
+
+// knowledgeBaseDir returns the directory holding the persistent index (rag.index_dir,
+// defaulting to ~/.cloudigest/kb)
+func knowledgeBaseDir() (string, error) {
+	if dir := viper.GetString("rag.index_dir"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %v", err)
+	}
+	return filepath.Join(home, ".cloudigest", "kb"), nil
+}
+
+// sourceConfig is an entry under rag.sources: a URL, a file or directory path, or a
+// documentation site to crawl
+type sourceConfig struct {
+	URL             string   `mapstructure:"url"`
+	Path            string   `mapstructure:"path"`
+	Crawl           string   `mapstructure:"crawl"`
+	Sitemap         string   `mapstructure:"sitemap"`
+	MaxDepth        int      `mapstructure:"max_depth"`
+	MaxPages        int      `mapstructure:"max_pages"`
+	Include         []string `mapstructure:"include"`
+	Exclude         []string `mapstructure:"exclude"`
+	Type            string   `mapstructure:"type"`
+	Name            string   `mapstructure:"name"`
+	Tags            []string `mapstructure:"tags"`
+	Provider        string   `mapstructure:"provider"`
+	RefreshInterval string   `mapstructure:"refresh_interval"`
+}
+
+// loadKnowledgeBase brings the persistent index in line with the configured sources.
+// Sources that are already indexed are not fetched again unless refresh is set or, for
+// URLs, their refresh interval has passed. Indexed URLs are refetched with conditional
+// requests, and refetched sources whose content didn't change are not re-embedded.
+// Sources added with `kb add` or `kb import` are kept, and refetched on refresh.
+// Progress and warnings are written to w.
+func loadKnowledgeBase(w io.Writer, r *rag.RAG, refresh bool) error {
+	// Get sources from config
+	var sources []sourceConfig
 	if err := viper.UnmarshalKey("rag.sources", &sources); err != nil {
 		return fmt.Errorf("failed to read document sources from config: %v", err)
 	}
 
-	if len(sources) == 0 {
-		fmt.Println("Warning: No document sources found in configuration. Using example documents.")
-		return loadExampleDocuments(r)
+	defaultInterval, err := parseInterval(viper.GetString("rag.refresh_interval"))
+	if err != nil {
+		return fmt.Errorf("invalid rag.refresh_interval: %v", err)
 	}
 
-	// Load content from each source
+	// Load content from each source; everything is embedded together at the end
+	var locations []string
+	var docs []rag.Document
 	for _, source := range sources {
-		fmt.Printf("Loading document from %s...\n", source.URL)
+		if source.Path != "" {
+			pathLocations, pathDocs := loadPathSource(w, r, source, refresh)
+			locations = append(locations, pathLocations...)
+			docs = append(docs, pathDocs...)
+			continue
+		}
 
-		// Fetch content from URL
-		resp, err := http.Get(source.URL)
-		if err != nil {
-			fmt.Printf("Warning: Failed to fetch document from %s: %v\n", source.URL, err)
+		interval := defaultInterval
+		if source.RefreshInterval != "" {
+			if interval, err = parseInterval(source.RefreshInterval); err != nil {
+				return fmt.Errorf("invalid refresh_interval for %s: %v", source.URL+source.Crawl, err)
+			}
+		}
+
+		if source.Crawl != "" {
+			crawlLocations, crawlDocs := loadCrawlSource(w, r, source, refresh, interval)
+			locations = append(locations, crawlLocations...)
+			docs = append(docs, crawlDocs...)
 			continue
 		}
-		defer resp.Body.Close()
 
-		content, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			fmt.Printf("Warning: Failed to read document from %s: %v\n", source.URL, err)
+		doc := rag.Document{
+			Source:   source.Name,
+			Type:     source.Type,
+			Location: source.URL,
+			Tags:     source.Tags,
+			Provider: source.Provider,
+			Origin:   rag.OriginConfig,
+		}
+		locations = append(locations, source.URL)
+		r.Relabel(doc)
+
+		if !refresh && !r.IsDue(source.URL, interval) {
+			continue
+		}
+
+		if doc, ok := refetchDocument(w, r, doc); ok {
+			docs = append(docs, doc)
+		}
+	}
+
+	// Drop sources that were removed from the configuration
+	for _, location := range r.PruneSources(rag.OriginConfig, locations) {
+		fmt.Fprintf(w, "Removed %s from the knowledge base\n", location)
+	}
+
+	// Imported files are paths on the exporter's machine, so only imported URLs are refetched
+	var manual int
+	for _, record := range r.Sources() {
+		if record.Origin != rag.OriginManual && record.Origin != rag.OriginImport {
+			continue
+		}
+		manual++
+		if record.Origin == rag.OriginImport && !rag.IsURL(record.Location) {
 			continue
 		}
+		if !refresh && (!rag.IsURL(record.Location) || !r.IsDue(record.Location, defaultInterval)) {
+			continue
+		}
+		if doc, ok := refetchDocument(w, r, rag.Document{
+			Source:   record.Name,
+			Type:     record.Type,
+			Location: record.Location,
+			Tags:     record.Tags,
+			Provider: record.Provider,
+			Origin:   record.Origin,
+		}); ok {
+			docs = append(docs, doc)
+		}
+	}
+	indexDocuments(w, r, docs)
+
+	if len(sources) == 0 && manual == 0 {
+		fmt.Fprintln(w, "Warning: No document sources found in configuration. Using example documents.")
+		if err := loadExampleDocuments(r); err != nil {
+			return err
+		}
+	} else {
+		r.PruneSources(rag.OriginBuiltin, nil)
+	}
+
+	return r.Save()
+}
+
+// loadPathSource reads the files under a configured path that are new or were modified
+// since they were last indexed, and returns the locations of all matching files together
+// with the documents to index. Relative paths are resolved against the directory of the
+// configuration file.
+func loadPathSource(w io.Writer, r *rag.RAG, source sourceConfig, refresh bool) ([]string, []rag.Document) {
+	root, name, docType := source.Path, source.Name, source.Type
+	if strings.HasPrefix(root, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			root = filepath.Join(home, root[2:])
+		}
+	}
+	if !filepath.IsAbs(root) && viper.ConfigFileUsed() != "" {
+		root = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), root)
+	}
+	if docType == "" {
+		docType = "documentation"
+	}
 
-		// Add document to RAG system
+	files, err := rag.ListFiles(root, source.Include, source.Exclude)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: %v\n", err)
+		return nil, nil
+	}
+
+	var locations []string
+	var docs []rag.Document
+	for _, file := range files {
+		docName := file.RelPath
+		if name != "" {
+			docName = name + ": " + file.RelPath
+			if len(files) == 1 && file.RelPath == filepath.Base(root) {
+				docName = name
+			}
+		}
 		doc := rag.Document{
-			Content: string(content),
-			Source:  source.Name,
-			Type:    source.Type,
+			Source:   docName,
+			Type:     docType,
+			Location: file.Path,
+			Tags:     source.Tags,
+			Provider: source.Provider,
+			Origin:   rag.OriginConfig,
+			ModTime:  file.ModTime,
 		}
 
-		if err := r.AddDocument(doc); err != nil {
-			fmt.Printf("Warning: Failed to add document from %s: %v\n", source.URL, err)
+		locations = append(locations, file.Path)
+		r.Relabel(doc)
+		if !refresh && r.IsFileCurrent(file.Path, file.ModTime) {
 			continue
 		}
+
+		if doc, ok := fetchDocument(w, doc); ok {
+			docs = append(docs, doc)
+		}
 	}
 
-	return nil
+	return locations, docs
+}
+
+// indexSource fetches a source and indexes it, printing a warning if that fails
+func indexSource(w io.Writer, r *rag.RAG, doc rag.Document) bool {
+	doc, ok := fetchDocument(w, doc)
+	return ok && indexDocuments(w, r, []rag.Document{doc}) == 1
+}
+
+// indexDocuments embeds fetched documents in batches, showing progress, and returns
+// how many were indexed. Failures are printed as warnings.
+func indexDocuments(w io.Writer, r *rag.RAG, docs []rag.Document) int {
+	if len(docs) == 0 {
+		return 0
+	}
+
+	progress := false
+	r.SetProgress(func(done, total int) {
+		fmt.Fprintf(w, "\rEmbedding chunks: %d/%d", done, total)
+		progress = true
+	})
+	changed, errs := r.IndexDocuments(docs)
+	r.SetProgress(nil)
+	if progress {
+		fmt.Fprintln(w)
+	}
+
+	indexed := 0
+	for i, doc := range docs {
+		if errs[i] != nil {
+			fmt.Fprintf(w, "Warning: Failed to add document from %s: %v\n", doc.Location, errs[i])
+			continue
+		}
+		if !changed[i] {
+			fmt.Fprintf(w, "%s is unchanged\n", doc.Location)
+		}
+		indexed++
+	}
+	return indexed
 }
 
 // loadExampleDocuments loads example documents when no sources are configured
@@ -158,15 +586,27 @@ func loadExampleDocuments(r *rag.RAG) error {
 		},
 	}
 
-	for _, doc := range documents {
-		if err := r.AddDocument(doc); err != nil {
+	var locations []string
+	for i := range documents {
+		documents[i].Location = "builtin:" + documents[i].Source
+		documents[i].Origin = rag.OriginBuiltin
+		locations = append(locations, documents[i].Location)
+	}
+	_, errs := r.IndexDocuments(documents)
+	for _, err := range errs {
+		if err != nil {
 			return err
 		}
 	}
+	r.PruneSources(rag.OriginBuiltin, locations)
 
 	return nil
 }
 
 func init() {
+	queryCmd.Flags().BoolVar(&queryRefresh, "refresh", false, "refetch all configured sources and re-embed the ones that changed")
+	queryCmd.Flags().BoolVar(&queryShowContext, "show-context", false, "print the retrieved chunks sent to the model as context")
+	queryCmd.Flags().BoolVar(&queryWeb, "web", false, "add the top web search results to the context (requires serper.api_key)")
+	queryFilter.register(queryCmd)
 	rootCmd.AddCommand(queryCmd)
 }
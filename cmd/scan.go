@@ -4,37 +4,34 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
+	"cloudigest/pkg/rag"
 	"cloudigest/pkg/scanner"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+var scanKnowledge bool
+
 var scanCmd = &cobra.Command{
 	Use:   "scan",
 	Short: "Scan infrastructure and provide optimization recommendations",
 	Long: `Scan your infrastructure (Kubernetes clusters, VMs, etc.) and provide detailed
-optimization recommendations for resources, performance, cost, and security.`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get OpenAI API key from config
-		openAIKey := viper.GetString("openai.api_key")
-		if openAIKey == "" {
-			return fmt.Errorf("OpenAI API key not found in configuration")
-		}
+optimization recommendations for resources, performance, cost, and security.
 
-		// Claude is optional - if missing, we'll use OpenAI exclusively
-		claudeKey := viper.GetString("claude.api_key")
-		useClaudeIfAvailable := claudeKey != "" && claudeKey != "your-claude-api-key-here"
-
-		// Initialize scanner based on available API keys
-		var infraScanner *scanner.Scanner
-		if useClaudeIfAvailable {
-			fmt.Println("Using Claude for infrastructure scanning")
-			infraScanner = scanner.NewScannerWithClaude(claudeKey)
-		} else {
-			fmt.Println("Using OpenAI for infrastructure scanning")
-			infraScanner = scanner.NewScanner(openAIKey)
+With --kb (or scanning.knowledge_base: true), knowledge base chunks relevant to each
+finding category are retrieved from the sources under rag.sources and given to the
+model, so recommendations follow your documented standards and cite them. The flag
+also applies to scan cloud and scan vm.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		infraScanner, err := newScanner()
+		if err != nil {
+			return err
 		}
 
 		// Scan Kubernetes cluster if enabled
@@ -52,15 +49,133 @@ optimization recommendations for resources, performance, cost, and security.`,
 				fmt.Println("\nKubernetes Scan Results:")
 				fmt.Println("========================")
 				fmt.Println(results)
+				saveScanResult("kubernetes", "Kubernetes Scan Results", results)
 			}
 		}
 
-		// Additional scanning could be added here for cloud providers
+		// Scan cloud inventory exports if configured
+		if files := viper.GetStringSlice("scanning.cloud_inventory"); len(files) > 0 {
+			fmt.Println("Scanning cloud inventory...")
+			if err := scanCloudInventory(infraScanner, files); err != nil {
+				fmt.Printf("Warning: failed to scan cloud inventory: %v\n", err)
+			}
+		}
 
 		return nil
 	},
 }
 
+// newScanner initializes a scanner based on the API keys available in the configuration
+func newScanner() (*scanner.Scanner, error) {
+	// Get OpenAI API key from config
+	openAIKey := viper.GetString("openai.api_key")
+	if openAIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not found in configuration")
+	}
+
+	// Claude is optional - if missing, we'll use OpenAI exclusively
+	claudeKey := viper.GetString("claude.api_key")
+	useClaudeIfAvailable := claudeKey != "" && claudeKey != "your-claude-api-key-here"
+
+	var infraScanner *scanner.Scanner
+	if useClaudeIfAvailable {
+		fmt.Println("Using Claude for infrastructure scanning")
+		infraScanner = scanner.NewScannerWithClaude(claudeKey)
+	} else {
+		fmt.Println("Using OpenAI for infrastructure scanning")
+		infraScanner = scanner.NewScanner(openAIKey)
+	}
+
+	if scanKnowledge || viper.GetBool("scanning.knowledge_base") {
+		knowledge, err := knowledgeSearch()
+		if err != nil {
+			return nil, err
+		}
+		infraScanner.SetKnowledge(knowledge)
+	}
+
+	return infraScanner, nil
+}
+
+// knowledgeSearch loads the knowledge base and returns a search over it for grounding
+// scans. Results are cached by topic, since every host of a fleet asks the same ones,
+// and searches are serialized because hosts are analyzed concurrently.
+func knowledgeSearch() (scanner.KnowledgeFunc, error) {
+	r, _, err := openKnowledgeBase()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Loading knowledge base...")
+	if err := loadKnowledgeBase(os.Stdout, r, false); err != nil {
+		return nil, fmt.Errorf("failed to load knowledge base: %v", err)
+	}
+	if r.Stats().Chunks == 0 {
+		fmt.Println("Warning: The knowledge base is empty; recommendations won't cite any sources.")
+	}
+
+	var mu sync.Mutex
+	cache := make(map[string][]scanner.Reference)
+	return func(topic string) ([]scanner.Reference, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if references, ok := cache[topic]; ok {
+			return references, nil
+		}
+
+		results, err := r.Search(topic)
+		if err != nil {
+			return nil, err
+		}
+		references := make([]scanner.Reference, len(results))
+		for i, result := range results {
+			references[i] = scanReference(result)
+		}
+		cache[topic] = references
+		return references, nil
+	}, nil
+}
+
+// scanReference converts a search result into a source for the scanner
+func scanReference(result rag.SearchResult) scanner.Reference {
+	location := result.Location
+	if strings.HasPrefix(location, "builtin:") {
+		location = ""
+	}
+	return scanner.Reference{Source: referenceLabel(result), Location: location, Content: result.Content}
+}
+
+// scanResultsDir is where the latest result of each scan is kept for chat --scan
+func scanResultsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %v", err)
+	}
+	return filepath.Join(home, ".cloudigest", "scans"), nil
+}
+
+// saveScanResult keeps the result of a scan, replacing the previous result of the same
+// kind, so that it can be loaded as context by chat --scan. Failures only warn.
+func saveScanResult(kind, title, results string) {
+	dir, err := scanResultsDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0755)
+	}
+	if err == nil {
+		name := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+				return r
+			}
+			return '_'
+		}, kind)
+		content := fmt.Sprintf("# %s\n\nScanned %s\n\n%s\n", title, time.Now().Format("2006-01-02 15:04 MST"), results)
+		err = os.WriteFile(filepath.Join(dir, name+".md"), []byte(content), 0644)
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to save scan results: %v\n", err)
+	}
+}
+
 func init() {
+	scanCmd.PersistentFlags().BoolVar(&scanKnowledge, "kb", false, "ground recommendations in the knowledge base and cite its sources")
 	rootCmd.AddCommand(scanCmd)
 }
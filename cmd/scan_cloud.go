@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"cloudigest/pkg/cloud"
+	"cloudigest/pkg/scanner"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cloudFindingsOnly bool
+
+var scanCloudCmd = &cobra.Command{
+	Use:   "cloud [export-file...]",
+	Short: "Scan cloud inventory exports and provide cost optimization recommendations",
+	Long: `Import cloud provider inventory exports, normalize them into a common resource
+model, run cost and security checks, and analyze the result.
+
+Supported exports (the format is detected automatically):
+  aws ec2 describe-instances --output json
+  aws ec2 describe-volumes --output json
+  az vm list -d --output json
+  gcloud compute instances list --format=json
+
+When no files are given, the files listed under scanning.cloud_inventory in the
+configuration are used. Only providers listed under scanning.cloud_providers are
+analyzed.
+
+Example:
+  aws ec2 describe-instances > instances.json
+  aws ec2 describe-volumes > volumes.json
+  cloudigest scan cloud instances.json volumes.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files := args
+		if len(files) == 0 {
+			files = viper.GetStringSlice("scanning.cloud_inventory")
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no inventory files given and scanning.cloud_inventory is not configured")
+		}
+
+		var infraScanner *scanner.Scanner
+		if !cloudFindingsOnly {
+			var err error
+			if infraScanner, err = newScanner(); err != nil {
+				return err
+			}
+		}
+
+		return scanCloudInventory(infraScanner, files)
+	},
+}
+
+// scanCloudInventory loads the export files, prints the deterministic findings and,
+// when a scanner is given, the model's cost analysis
+func scanCloudInventory(infraScanner *scanner.Scanner, files []string) error {
+	var resources []cloud.Resource
+	for _, file := range files {
+		fmt.Printf("Importing %s...\n", file)
+		imported, err := cloud.LoadFile(file)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, imported...)
+	}
+
+	resources = cloud.FilterProviders(resources, viper.GetStringSlice("scanning.cloud_providers"))
+	if len(resources) == 0 {
+		return fmt.Errorf("no resources from enabled cloud providers found in inventory files")
+	}
+
+	findings := cloud.Analyze(resources)
+
+	fmt.Println("\nCloud Inventory Findings:")
+	fmt.Println("=========================")
+	if len(findings) == 0 {
+		fmt.Println("No issues found.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tCATEGORY\tRESOURCE\tFINDING")
+		for _, f := range findings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Severity, f.Category, f.ResourceID, f.Message)
+		}
+		w.Flush()
+	}
+
+	if infraScanner == nil {
+		return nil
+	}
+
+	fmt.Printf("\nAnalyzing %d cloud resource(s)...\n", len(resources))
+	results, err := infraScanner.ScanCloudInventory(map[string]interface{}{
+		"summary":   cloud.Summary(resources),
+		"resources": resources,
+		"findings":  findings,
+	}, findingTopics(resources, findings))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nCloud Scan Results:")
+	fmt.Println("===================")
+	fmt.Println(results)
+	saveScanResult("cloud", "Cloud Scan Results", results)
+	return nil
+}
+
+// maxTopicFindings bounds how many finding messages describe a category in its topic
+const maxTopicFindings = 5
+
+// findingTopics describes each category of findings, with the providers involved and the
+// distinct issues found, as a knowledge base query
+func findingTopics(resources []cloud.Resource, findings []cloud.Finding) []string {
+	providerSet := make(map[string]bool)
+	for _, r := range resources {
+		providerSet[r.Provider] = true
+	}
+	var providers []string
+	for provider := range providerSet {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	var categories []string
+	messages := make(map[string][]string)
+	for _, f := range findings {
+		if _, ok := messages[f.Category]; !ok {
+			categories = append(categories, f.Category)
+		}
+		if len(messages[f.Category]) < maxTopicFindings && !containsString(messages[f.Category], f.Message) {
+			messages[f.Category] = append(messages[f.Category], f.Message)
+		}
+	}
+
+	var topics []string
+	for _, category := range categories {
+		topics = append(topics, fmt.Sprintf("%s cloud %s best practices: %s",
+			strings.Join(providers, ", "), category, strings.Join(messages[category], "; ")))
+	}
+	if len(topics) == 0 {
+		topics = append(topics, strings.Join(providers, ", ")+" cloud cost optimization best practices")
+	}
+	return topics
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	scanCloudCmd.Flags().BoolVar(&cloudFindingsOnly, "findings", false, "only print the deterministic findings without model analysis")
+	scanCmd.AddCommand(scanCloudCmd)
+}
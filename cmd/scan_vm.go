@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"cloudigest/pkg/hostinfo"
+	"cloudigest/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	vmFactsOnly      bool
+	vmSSHTargets     []string
+	vmInventoryFile  string
+	vmWorkers        int
+	vmIdentityFiles  []string
+	vmKnownHostsFile string
+	vmSSHTimeout     time.Duration
+)
+
+// vmScanResult holds the outcome of scanning a single host
+type vmScanResult struct {
+	target   string
+	info     *hostinfo.HostInfo
+	analysis string
+	err      error
+}
+
+var scanVMCmd = &cobra.Command{
+	Use:   "vm",
+	Short: "Scan virtual machines and provide optimization recommendations",
+	Long: `Collect CPU, memory, disk, filesystem, load, process, open port and kernel/OS
+information from Linux hosts and analyze it for resource, performance, cost and
+security improvements.
+
+Without --ssh or --inventory the local host is scanned. Remote hosts are reached
+over SSH using the SSH agent and/or private keys, and host keys are verified
+against known_hosts. An inventory file lists one [user@]host[:port] per line.
+
+Example:
+  cloudigest scan vm
+  cloudigest scan vm --facts
+  cloudigest scan vm --ssh ubuntu@10.0.0.12 --ssh ec2-user@10.0.0.13:2222
+  cloudigest scan vm --inventory hosts.txt --workers 8`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets, err := vmTargets()
+		if err != nil {
+			return err
+		}
+
+		if len(targets) == 0 {
+			return scanLocalVM()
+		}
+
+		var infraScanner *scanner.Scanner
+		if !vmFactsOnly {
+			if infraScanner, err = newScanner(); err != nil {
+				return err
+			}
+		}
+
+		opts := hostinfo.SSHOptions{
+			IdentityFiles:  vmIdentityFiles,
+			KnownHostsFile: vmKnownHostsFile,
+			Timeout:        vmSSHTimeout,
+		}
+
+		fmt.Printf("Scanning %d host(s) with %d worker(s)...\n", len(targets), vmWorkers)
+		results := scanRemoteVMs(infraScanner, targets, opts)
+
+		failed := 0
+		for _, result := range results {
+			if result.err != nil {
+				failed++
+				fmt.Printf("\nWarning: failed to scan %s: %v\n", result.target, result.err)
+				continue
+			}
+			if vmFactsOnly {
+				if err := printFacts(result.info); err != nil {
+					return err
+				}
+				continue
+			}
+
+			title := fmt.Sprintf("VM Scan Results: %s (%s)", result.info.Hostname, result.target)
+			fmt.Println("\n" + title)
+			fmt.Println(strings.Repeat("=", len(title)))
+			fmt.Println(result.analysis)
+			saveScanResult("vm-"+result.info.Hostname, title, result.analysis)
+		}
+
+		if len(results) > 1 {
+			if err := printFleetReport(infraScanner, results); err != nil {
+				return err
+			}
+		}
+
+		if failed == len(results) {
+			return fmt.Errorf("all %d host(s) failed to scan", failed)
+		}
+		return nil
+	},
+}
+
+// vmTargets combines the --ssh targets with the hosts listed in the inventory file
+func vmTargets() ([]hostinfo.Target, error) {
+	var targets []hostinfo.Target
+	for _, s := range vmSSHTargets {
+		target, err := hostinfo.ParseTarget(s)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	if vmInventoryFile != "" {
+		inventory, err := hostinfo.LoadInventory(vmInventoryFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(inventory) == 0 {
+			return nil, fmt.Errorf("inventory %s contains no hosts", vmInventoryFile)
+		}
+		targets = append(targets, inventory...)
+	}
+
+	return targets, nil
+}
+
+func scanLocalVM() error {
+	fmt.Println("Collecting host information...")
+	info, err := hostinfo.Collect()
+	if err != nil {
+		return fmt.Errorf("failed to collect host information: %v", err)
+	}
+
+	if vmFactsOnly {
+		return printFacts(info)
+	}
+
+	infraScanner, err := newScanner()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Analyzing %s (%s, %d vCPU, %d MB RAM)...\n",
+		info.Hostname, info.OS.Name, info.CPU.Threads, info.Memory.TotalMB)
+	results, err := analyzeHost(infraScanner, info)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nVM Scan Results:")
+	fmt.Println("================")
+	fmt.Println(results)
+	saveScanResult("vm-"+info.Hostname, "VM Scan Results: "+info.Hostname, results)
+	return nil
+}
+
+// scanRemoteVMs collects and analyzes each target with at most vmWorkers hosts
+// in flight. Results are returned in the same order as targets.
+func scanRemoteVMs(infraScanner *scanner.Scanner, targets []hostinfo.Target, opts hostinfo.SSHOptions) []vmScanResult {
+	results := make([]vmScanResult, len(targets))
+	workers := vmWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target hostinfo.Target) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := vmScanResult{target: target.String()}
+			result.info, result.err = hostinfo.CollectRemote(target, opts)
+			if result.err == nil && infraScanner != nil {
+				fmt.Printf("Analyzing %s...\n", target)
+				result.analysis, result.err = analyzeHost(infraScanner, result.info)
+			}
+			results[i] = result
+		}(i, target)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func analyzeHost(infraScanner *scanner.Scanner, info *hostinfo.HostInfo) (string, error) {
+	vmInfo, err := info.ToMap()
+	if err != nil {
+		return "", err
+	}
+
+	results, err := infraScanner.ScanVirtualMachine(vmInfo)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan virtual machine: %v", err)
+	}
+	return results, nil
+}
+
+// printFleetReport prints a table comparing every successfully scanned host,
+// followed by fleet-level recommendations when a scanner is available
+func printFleetReport(infraScanner *scanner.Scanner, results []vmScanResult) error {
+	fmt.Println("\nFleet Summary:")
+	fmt.Println("==============")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tHOSTNAME\tOS\tKERNEL\tVCPU\tCPU%\tMEM(MB)\tMEM%\tLOAD/VCPU\tMAX FS%\tPORTS")
+
+	var fleetInfo []map[string]interface{}
+	hostAnalyses := make(map[string]string)
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\n", result.target)
+			continue
+		}
+
+		info := result.info
+		maxFS := 0.0
+		for _, fs := range info.Filesystems {
+			if fs.UsedPercent > maxFS {
+				maxFS = fs.UsedPercent
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%.1f\t%d\t%.1f\t%.2f\t%.1f\t%d\n",
+			result.target, info.Hostname, info.OS.Name, info.OS.KernelRelease, info.CPU.Threads,
+			info.CPU.UsagePercent, info.Memory.TotalMB, info.Memory.UsedPercent,
+			info.Load.LoadPerThread, maxFS, len(info.OpenPorts))
+
+		fleetInfo = append(fleetInfo, map[string]interface{}{
+			"target":            result.target,
+			"hostname":          info.Hostname,
+			"os":                info.OS.Name,
+			"kernel":            info.OS.KernelRelease,
+			"vcpus":             info.CPU.Threads,
+			"cpuUsagePercent":   info.CPU.UsagePercent,
+			"memoryTotalMB":     info.Memory.TotalMB,
+			"memoryUsedPercent": info.Memory.UsedPercent,
+			"loadPerVCPU":       info.Load.LoadPerThread,
+			"maxFsUsedPercent":  maxFS,
+			"openPorts":         info.OpenPorts,
+		})
+		hostAnalyses[result.target] = result.analysis
+	}
+	w.Flush()
+
+	if infraScanner == nil || len(fleetInfo) < 2 {
+		return nil
+	}
+
+	fmt.Println("Generating fleet recommendations...")
+	analysis, err := infraScanner.AnalyzeFleet(fleetInfo, hostAnalyses)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nFleet Recommendations:")
+	fmt.Println("======================")
+	fmt.Println(analysis)
+	saveScanResult("fleet", "Fleet Recommendations", analysis)
+	return nil
+}
+
+func printFacts(info *hostinfo.HostInfo) error {
+	factsJSON, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal host information: %v", err)
+	}
+	fmt.Println(string(factsJSON))
+	return nil
+}
+
+func init() {
+	scanVMCmd.Flags().BoolVar(&vmFactsOnly, "facts", false, "print the collected host information as JSON without analyzing it")
+	scanVMCmd.Flags().StringArrayVar(&vmSSHTargets, "ssh", nil, "scan a remote host over SSH ([user@]host[:port]); can be repeated")
+	scanVMCmd.Flags().StringVar(&vmInventoryFile, "inventory", "", "file listing remote hosts to scan, one [user@]host[:port] per line")
+	scanVMCmd.Flags().IntVar(&vmWorkers, "workers", 4, "maximum number of hosts scanned concurrently")
+	scanVMCmd.Flags().StringArrayVarP(&vmIdentityFiles, "identity", "i", nil, "private key for SSH authentication (default ~/.ssh/id_ed25519, id_ecdsa, id_rsa)")
+	scanVMCmd.Flags().StringVar(&vmKnownHostsFile, "known-hosts", "", "known_hosts file used to verify host keys (default ~/.ssh/known_hosts)")
+	scanVMCmd.Flags().DurationVar(&vmSSHTimeout, "ssh-timeout", 15*time.Second, "timeout for establishing each SSH connection")
+	scanCmd.AddCommand(scanVMCmd)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"cloudigest/pkg/rag"
+	"cloudigest/pkg/search"
+
+	"github.com/spf13/viper"
+)
+
+// defaultWebResults is how many search results are fetched when serper.results isn't set
+const defaultWebResults = 3
+
+// newSearchProvider creates the web search provider from the serper settings
+func newSearchProvider() (search.Provider, error) {
+	apiKey := viper.GetString("serper.api_key")
+	if apiKey == "" || apiKey == "your-serper-api-key-here" {
+		return nil, fmt.Errorf("Serper API key not found in configuration")
+	}
+	return search.NewSerper(apiKey, viper.GetString("serper.base_url")), nil
+}
+
+// webDocuments searches the web for a question and fetches the top results. A page
+// that can't be fetched or has no text is represented by its search snippet.
+func webDocuments(provider search.Provider, question string) ([]rag.Document, error) {
+	n := viper.GetInt("serper.results")
+	if n <= 0 {
+		n = defaultWebResults
+	}
+
+	fmt.Printf("Searching the web with %s...\n", provider.Name())
+	results, err := provider.Search(context.Background(), question, n)
+	if err != nil {
+		return nil, fmt.Errorf("web search failed: %v", err)
+	}
+	if len(results) == 0 {
+		fmt.Println("Warning: The web search returned no results.")
+	}
+
+	var docs []rag.Document
+	for _, result := range results {
+		doc := rag.Document{
+			Source:   result.Title,
+			Type:     "web",
+			Location: result.URL,
+			Provider: rag.InferProvider(result.URL),
+		}
+		if doc.Source == "" {
+			doc.Source = result.URL
+		}
+
+		fmt.Printf("Fetching %s...\n", result.URL)
+		fetched, err := fetchSource(result.URL, nil)
+		if err == nil {
+			doc.Content, doc.Metadata, err = rag.Extract(fetched.content, fetched.contentType, result.URL)
+		}
+		if err != nil || doc.Content == "" {
+			if err != nil {
+				fmt.Printf("Warning: %v; using the search snippet instead\n", err)
+			}
+			doc.Content = result.Snippet
+			doc.Metadata = map[string]string{"format": "text"}
+		}
+		if doc.Content != "" {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,143 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type awsTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+type awsInstance struct {
+	InstanceID   string `json:"InstanceId"`
+	InstanceType string `json:"InstanceType"`
+	State        struct {
+		Name string `json:"Name"`
+	} `json:"State"`
+	Placement struct {
+		AvailabilityZone string `json:"AvailabilityZone"`
+	} `json:"Placement"`
+	PublicIPAddress     string     `json:"PublicIpAddress"`
+	PlatformDetails     string     `json:"PlatformDetails"`
+	InstanceLifecycle   string     `json:"InstanceLifecycle"`
+	LaunchTime          *time.Time `json:"LaunchTime"`
+	Tags                []awsTag   `json:"Tags"`
+	BlockDeviceMappings []struct {
+		Ebs struct {
+			VolumeID string `json:"VolumeId"`
+		} `json:"Ebs"`
+	} `json:"BlockDeviceMappings"`
+}
+
+type awsVolume struct {
+	VolumeID         string   `json:"VolumeId"`
+	Size             int      `json:"Size"`
+	VolumeType       string   `json:"VolumeType"`
+	State            string   `json:"State"`
+	Encrypted        bool     `json:"Encrypted"`
+	AvailabilityZone string   `json:"AvailabilityZone"`
+	Tags             []awsTag `json:"Tags"`
+	Attachments      []struct {
+		InstanceID string `json:"InstanceId"`
+	} `json:"Attachments"`
+}
+
+// parseAWSInstances normalizes `aws ec2 describe-instances` output
+func parseAWSInstances(data []byte) ([]Resource, error) {
+	var doc struct {
+		Reservations []struct {
+			OwnerID   string        `json:"OwnerId"`
+			Instances []awsInstance `json:"Instances"`
+		} `json:"Reservations"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-instances output: %v", err)
+	}
+
+	var resources []Resource
+	for _, reservation := range doc.Reservations {
+		for _, inst := range reservation.Instances {
+			tags := awsTags(inst.Tags)
+			resource := Resource{
+				Provider:   "aws",
+				Kind:       KindInstance,
+				ID:         inst.InstanceID,
+				Account:    reservation.OwnerID,
+				Name:       tags["Name"],
+				Region:     awsRegion(inst.Placement.AvailabilityZone),
+				Zone:       inst.Placement.AvailabilityZone,
+				Size:       inst.InstanceType,
+				State:      inst.State.Name,
+				Platform:   inst.PlatformDetails,
+				PublicIP:   inst.PublicIPAddress,
+				Spot:       inst.InstanceLifecycle == "spot",
+				LaunchTime: inst.LaunchTime,
+				Tags:       tags,
+			}
+			for _, mapping := range inst.BlockDeviceMappings {
+				if mapping.Ebs.VolumeID != "" {
+					resource.Volumes = append(resource.Volumes, mapping.Ebs.VolumeID)
+				}
+			}
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources, nil
+}
+
+// parseAWSVolumes normalizes `aws ec2 describe-volumes` output
+func parseAWSVolumes(data []byte) ([]Resource, error) {
+	var doc struct {
+		Volumes []awsVolume `json:"Volumes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-volumes output: %v", err)
+	}
+
+	var resources []Resource
+	for _, vol := range doc.Volumes {
+		tags := awsTags(vol.Tags)
+		resource := Resource{
+			Provider:   "aws",
+			Kind:       KindVolume,
+			ID:         vol.VolumeID,
+			Name:       tags["Name"],
+			Region:     awsRegion(vol.AvailabilityZone),
+			Zone:       vol.AvailabilityZone,
+			State:      vol.State,
+			DiskGB:     vol.Size,
+			VolumeType: vol.VolumeType,
+			Encrypted:  boolPtr(vol.Encrypted),
+			Tags:       tags,
+		}
+		for _, attachment := range vol.Attachments {
+			resource.AttachedTo = append(resource.AttachedTo, attachment.InstanceID)
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func awsTags(tags []awsTag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[tag.Key] = tag.Value
+	}
+	return result
+}
+
+// awsRegion derives the region from an availability zone, e.g. us-east-1a -> us-east-1
+func awsRegion(zone string) string {
+	if len(zone) > 1 && zone[len(zone)-1] >= 'a' && zone[len(zone)-1] <= 'z' {
+		return zone[:len(zone)-1]
+	}
+	return zone
+}
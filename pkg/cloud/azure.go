@@ -0,0 +1,125 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type azureDisk struct {
+	Name        string `json:"name"`
+	DiskSizeGB  int    `json:"diskSizeGb"`
+	ManagedDisk *struct {
+		ID                 string `json:"id"`
+		StorageAccountType string `json:"storageAccountType"`
+	} `json:"managedDisk"`
+	EncryptionSettings *struct {
+		Enabled bool `json:"enabled"`
+	} `json:"encryptionSettings"`
+}
+
+type azureVM struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Location        string            `json:"location"`
+	Zones           []string          `json:"zones"`
+	Priority        string            `json:"priority"`
+	PowerState      string            `json:"powerState"` // only present with `az vm list -d`
+	PublicIPs       string            `json:"publicIps"`  // only present with `az vm list -d`
+	Tags            map[string]string `json:"tags"`
+	HardwareProfile struct {
+		VMSize string `json:"vmSize"`
+	} `json:"hardwareProfile"`
+	StorageProfile struct {
+		OSDisk    azureDisk   `json:"osDisk"`
+		DataDisks []azureDisk `json:"dataDisks"`
+	} `json:"storageProfile"`
+}
+
+// parseAzureVMs normalizes `az vm list` output. Attached OS and data disks are
+// reported as volume resources since az vm list embeds them in the VM.
+func parseAzureVMs(data []byte) ([]Resource, error) {
+	var vms []azureVM
+	if err := json.Unmarshal(data, &vms); err != nil {
+		return nil, fmt.Errorf("failed to parse az vm list output: %v", err)
+	}
+
+	var resources []Resource
+	for _, vm := range vms {
+		zone := ""
+		if len(vm.Zones) > 0 {
+			zone = vm.Zones[0]
+		}
+
+		// powerState is only reported by `az vm list -d`
+		state := strings.ToLower(strings.TrimPrefix(vm.PowerState, "VM "))
+		if state == "" {
+			state = "unknown"
+		}
+
+		instance := Resource{
+			Provider: "azure",
+			Kind:     KindInstance,
+			ID:       vm.ID,
+			Account:  azureScope(vm.ID),
+			Name:     vm.Name,
+			Region:   vm.Location,
+			Zone:     zone,
+			Size:     vm.HardwareProfile.VMSize,
+			State:    state,
+			PublicIP: vm.PublicIPs,
+			Spot:     vm.Priority == "Spot",
+			Tags:     vm.Tags,
+		}
+
+		disks := append([]azureDisk{vm.StorageProfile.OSDisk}, vm.StorageProfile.DataDisks...)
+		for _, disk := range disks {
+			if disk.Name == "" {
+				continue
+			}
+			volume := Resource{
+				Provider:   "azure",
+				Kind:       KindVolume,
+				ID:         disk.Name,
+				Account:    instance.Account,
+				Name:       disk.Name,
+				Region:     vm.Location,
+				Zone:       zone,
+				State:      "in-use",
+				DiskGB:     disk.DiskSizeGB,
+				AttachedTo: []string{vm.Name},
+				Tags:       vm.Tags,
+			}
+			if disk.ManagedDisk != nil {
+				if disk.ManagedDisk.ID != "" {
+					volume.ID = disk.ManagedDisk.ID
+				}
+				volume.VolumeType = disk.ManagedDisk.StorageAccountType
+			}
+			// Managed disks always have platform-managed encryption at rest, so only
+			// unmanaged (VHD) disks without Azure Disk Encryption are unencrypted
+			ade := disk.EncryptionSettings != nil && disk.EncryptionSettings.Enabled
+			volume.Encrypted = boolPtr(disk.ManagedDisk != nil || ade)
+
+			instance.Volumes = append(instance.Volumes, volume.ID)
+			resources = append(resources, volume)
+		}
+
+		resources = append(resources, instance)
+	}
+
+	return resources, nil
+}
+
+// azureScope returns the subscription and resource group of an Azure resource ID, e.g.
+// /subscriptions/S/resourceGroups/RG/providers/... -> s/rg. Resource group names are
+// case-insensitive and az reports them in either case.
+func azureScope(id string) string {
+	parts := strings.Split(strings.ToLower(id), "/")
+	for i := 0; i+3 < len(parts); i++ {
+		if parts[i] == "subscriptions" && parts[i+2] == "resourcegroups" {
+			return parts[i+1] + "/" + parts[i+3]
+		}
+	}
+	return ""
+}
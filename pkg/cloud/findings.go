@@ -0,0 +1,134 @@
+package cloud
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// previousGenerationFamilies are instance families with cheaper or faster current-generation replacements
+var previousGenerationFamilies = map[string]string{
+	// AWS
+	"t1": "t3", "t2": "t3", "m1": "m6i", "m3": "m6i", "m4": "m6i", "c1": "c6i", "c3": "c6i",
+	"c4": "c6i", "r3": "r6i", "r4": "r6i", "i2": "i4i", "d2": "d3", "g2": "g5", "p2": "p4d",
+	// GCP
+	"n1": "n2/e2", "f1": "e2", "g1": "e2",
+}
+
+// Analyze runs deterministic cost, security and operations checks over the inventory
+func Analyze(resources []Resource) []Finding {
+	var findings []Finding
+	add := func(severity, category string, r Resource, format string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Severity:   severity,
+			Category:   category,
+			ResourceID: displayID(r),
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Volumes refer to their instances by ID on AWS and by name on Azure and GCP
+	instances := make(map[string]Resource)
+	for _, r := range resources {
+		if r.Kind != KindInstance {
+			continue
+		}
+		// Unnamed instances must not share the empty key
+		if r.ID != "" {
+			instances[r.ID] = r
+		}
+		if r.Name != "" {
+			instances[instanceKey(r, r.Name)] = r
+		}
+	}
+
+	for _, r := range resources {
+		switch r.Kind {
+		case KindInstance:
+			if r.State == "stopped" || r.State == "deallocated" {
+				if len(r.Volumes) > 0 {
+					add("medium", "cost", r, "instance is %s but still has %d attached volume(s) that continue to incur storage charges", r.State, len(r.Volumes))
+				}
+			}
+			if family := instanceFamily(r.Provider, r.Size); family != "" {
+				if replacement, ok := previousGenerationFamilies[family]; ok {
+					add("low", "cost", r, "%s is a previous-generation instance type; consider migrating to %s", r.Size, replacement)
+				}
+			}
+			if r.PublicIP != "" {
+				add("medium", "security", r, "instance has a public IP address (%s)", r.PublicIP)
+			}
+			if r.State == "stopped" && r.PublicIP != "" && r.Provider == "aws" {
+				add("low", "cost", r, "stopped instance holds a public IP; Elastic IPs are billed while unattached to a running instance")
+			}
+			if r.Provider == "azure" && r.State == "stopped" {
+				add("high", "cost", r, "VM is stopped but not deallocated, so compute is still billed; use az vm deallocate")
+			}
+
+		case KindVolume:
+			if len(r.AttachedTo) == 0 || r.State == "available" || r.State == "unattached" {
+				add("high", "cost", r, "%d GB volume is not attached to any instance", r.DiskGB)
+			}
+			if r.Encrypted != nil && !*r.Encrypted {
+				add("high", "security", r, "volume is not encrypted at rest")
+			}
+			if r.Provider == "aws" && r.VolumeType == "gp2" {
+				add("low", "cost", r, "gp2 volume can be migrated to gp3 for roughly 20%% lower cost and baseline 3000 IOPS")
+			}
+			if r.Provider == "aws" && (r.VolumeType == "io1" || r.VolumeType == "io2") {
+				add("low", "cost", r, "provisioned IOPS (%s) volume; confirm the workload needs it over gp3", r.VolumeType)
+			}
+			for _, id := range r.AttachedTo {
+				if id == "" {
+					continue
+				}
+				inst, ok := instances[id]
+				if !ok {
+					inst, ok = instances[instanceKey(r, id)]
+				}
+				if ok && (inst.State == "stopped" || inst.State == "deallocated") {
+					add("low", "cost", r, "volume is attached to %s instance %s", inst.State, displayID(inst))
+					break
+				}
+			}
+		}
+
+		if len(r.Tags) == 0 {
+			add("low", "operations", r, "%s has no tags/labels, so cost allocation and ownership are unknown", r.Kind)
+		}
+	}
+
+	severityRank := map[string]int{"high": 0, "medium": 1, "low": 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] < severityRank[findings[j].Severity]
+	})
+
+	return findings
+}
+
+// instanceFamily extracts the family from an instance type, e.g. m4.large -> m4, n1-standard-4 -> n1
+func instanceFamily(provider, size string) string {
+	switch provider {
+	case "aws":
+		family, _, _ := strings.Cut(size, ".")
+		return family
+	case "gcp":
+		family, _, _ := strings.Cut(size, "-")
+		return family
+	}
+	return ""
+}
+
+// instanceKey scopes an instance name by provider, account and zone, since names are only
+// unique within an Azure resource group or a GCP project zone. Volumes share the account
+// and zone of their instance.
+func instanceKey(r Resource, name string) string {
+	return strings.Join([]string{r.Provider, r.Account, r.Zone, name}, "/")
+}
+
+func displayID(r Resource) string {
+	if r.Name != "" && r.Name != r.ID {
+		return fmt.Sprintf("%s/%s (%s)", r.Provider, lastSegment(r.ID), r.Name)
+	}
+	return r.Provider + "/" + lastSegment(r.ID)
+}
@@ -0,0 +1,65 @@
+package cloud
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeStoppedInstanceVolumes(t *testing.T) {
+	// Two Azure subscriptions and two GCP projects each have an instance called "web";
+	// only the stopped one's volume should be reported
+	azure := `[
+		{"id": "/subscriptions/prod/resourceGroups/App/providers/Microsoft.Compute/virtualMachines/web", "name": "web",
+		 "location": "westeurope", "powerState": "VM running", "tags": {"team": "a"}, "hardwareProfile": {"vmSize": "Standard_D2s_v5"},
+		 "storageProfile": {"osDisk": {"name": "prod-os", "diskSizeGb": 30, "managedDisk": {"id": "/subscriptions/prod/disks/prod-os"}}}},
+		{"id": "/subscriptions/dev/resourceGroups/app/providers/Microsoft.Compute/virtualMachines/web", "name": "web",
+		 "location": "westeurope", "powerState": "VM deallocated", "tags": {"team": "a"}, "hardwareProfile": {"vmSize": "Standard_D2s_v5"},
+		 "storageProfile": {"osDisk": {"name": "dev-os", "diskSizeGb": 30, "managedDisk": {"id": "/subscriptions/dev/disks/dev-os"}}}}
+	]`
+	gcp := `[
+		{"id": "1", "name": "web", "selfLink": "https://www.googleapis.com/compute/v1/projects/prod/zones/us-central1-a/instances/web",
+		 "zone": "projects/prod/zones/us-central1-a", "machineType": "zones/us-central1-a/machineTypes/e2-small", "status": "TERMINATED",
+		 "labels": {"team": "a"}, "disks": [{"deviceName": "boot", "source": "projects/prod/zones/us-central1-a/disks/prod-boot", "diskSizeGb": "10"}]},
+		{"id": "2", "name": "web", "selfLink": "https://www.googleapis.com/compute/v1/projects/dev/zones/us-central1-a/instances/web",
+		 "zone": "projects/dev/zones/us-central1-a", "machineType": "zones/us-central1-a/machineTypes/e2-small", "status": "RUNNING",
+		 "labels": {"team": "a"}, "disks": [{"deviceName": "boot", "source": "projects/dev/zones/us-central1-a/disks/dev-boot", "diskSizeGb": "10"}]}
+	]`
+
+	var resources []Resource
+	for _, data := range []string{azure, gcp} {
+		parsed, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		resources = append(resources, parsed...)
+	}
+
+	var attached []string
+	for _, f := range Analyze(resources) {
+		if strings.HasPrefix(f.Message, "volume is attached to") {
+			attached = append(attached, f.ResourceID)
+		}
+	}
+	want := []string{"azure/dev-os (dev-os)", "gcp/prod-boot (boot)"}
+	if strings.Join(attached, ",") != strings.Join(want, ",") {
+		t.Errorf("volumes attached to stopped instances = %v, want %v", attached, want)
+	}
+}
+
+func TestResourceScopes(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"azure", azureScope("/subscriptions/1234/resourceGroups/My-RG/providers/Microsoft.Compute/virtualMachines/web"), "1234/my-rg"},
+		{"azure without group", azureScope("/subscriptions/1234"), ""},
+		{"gcp", gcpProject("https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-b/instances/web"), "my-project"},
+		{"gcp without project", gcpProject(""), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s scope = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
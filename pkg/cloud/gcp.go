@@ -0,0 +1,135 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type gcpInstance struct {
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	SelfLink          string            `json:"selfLink"`
+	Zone              string            `json:"zone"`
+	MachineType       string            `json:"machineType"`
+	Status            string            `json:"status"`
+	CreationTimestamp string            `json:"creationTimestamp"`
+	Labels            map[string]string `json:"labels"`
+	Scheduling        struct {
+		Preemptible       bool   `json:"preemptible"`
+		ProvisioningModel string `json:"provisioningModel"`
+	} `json:"scheduling"`
+	Disks []struct {
+		DeviceName string `json:"deviceName"`
+		Source     string `json:"source"`
+		DiskSizeGB string `json:"diskSizeGb"`
+		Boot       bool   `json:"boot"`
+	} `json:"disks"`
+	NetworkInterfaces []struct {
+		AccessConfigs []struct {
+			NatIP string `json:"natIP"`
+		} `json:"accessConfigs"`
+	} `json:"networkInterfaces"`
+}
+
+// gcpStates maps Compute Engine statuses onto the names used by the other providers
+var gcpStates = map[string]string{
+	"RUNNING":      "running",
+	"TERMINATED":   "stopped",
+	"STOPPED":      "stopped",
+	"SUSPENDED":    "stopped",
+	"STOPPING":     "stopping",
+	"PROVISIONING": "pending",
+	"STAGING":      "pending",
+}
+
+// parseGCPInstances normalizes `gcloud compute instances list --format=json` output.
+// Attached disks are reported as volume resources.
+func parseGCPInstances(data []byte) ([]Resource, error) {
+	var instances []gcpInstance
+	if err := json.Unmarshal(data, &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse gcloud compute instances list output: %v", err)
+	}
+
+	var resources []Resource
+	for _, inst := range instances {
+		zone := lastSegment(inst.Zone)
+		state, ok := gcpStates[inst.Status]
+		if !ok {
+			state = strings.ToLower(inst.Status)
+		}
+
+		instance := Resource{
+			Provider: "gcp",
+			Kind:     KindInstance,
+			ID:       inst.ID,
+			Account:  gcpProject(inst.SelfLink),
+			Name:     inst.Name,
+			Region:   gcpRegion(zone),
+			Zone:     zone,
+			Size:     lastSegment(inst.MachineType),
+			State:    state,
+			Spot:     inst.Scheduling.Preemptible || inst.Scheduling.ProvisioningModel == "SPOT",
+			Tags:     inst.Labels,
+		}
+		if created, err := time.Parse(time.RFC3339, inst.CreationTimestamp); err == nil {
+			instance.LaunchTime = &created
+		}
+		for _, nic := range inst.NetworkInterfaces {
+			for _, access := range nic.AccessConfigs {
+				if access.NatIP != "" && instance.PublicIP == "" {
+					instance.PublicIP = access.NatIP
+				}
+			}
+		}
+
+		for _, disk := range inst.Disks {
+			sizeGB, _ := strconv.Atoi(disk.DiskSizeGB)
+			id := lastSegment(disk.Source)
+			if id == "" {
+				id = disk.DeviceName
+			}
+			// Persistent disks are always encrypted at rest with Google-managed keys
+			resources = append(resources, Resource{
+				Provider:   "gcp",
+				Kind:       KindVolume,
+				ID:         id,
+				Account:    instance.Account,
+				Name:       disk.DeviceName,
+				Region:     instance.Region,
+				Zone:       zone,
+				State:      "in-use",
+				DiskGB:     sizeGB,
+				Encrypted:  boolPtr(true),
+				AttachedTo: []string{inst.Name},
+				Tags:       inst.Labels,
+			})
+			instance.Volumes = append(instance.Volumes, id)
+		}
+
+		resources = append(resources, instance)
+	}
+
+	return resources, nil
+}
+
+// gcpRegion derives the region from a zone, e.g. us-central1-a -> us-central1
+func gcpRegion(zone string) string {
+	if i := strings.LastIndexByte(zone, '-'); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
+
+// gcpProject returns the project of a Compute Engine resource URL, e.g.
+// https://www.googleapis.com/compute/v1/projects/my-project/zones/... -> my-project
+func gcpProject(link string) string {
+	_, rest, ok := strings.Cut(link, "/projects/")
+	if !ok {
+		return ""
+	}
+	project, _, _ := strings.Cut(rest, "/")
+	return project
+}
@@ -0,0 +1,141 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Resource kinds shared by every provider
+const (
+	KindInstance = "instance"
+	KindVolume   = "volume"
+)
+
+// Resource is a provider-neutral view of a compute instance or block volume
+type Resource struct {
+	Provider   string            `json:"provider"`
+	Kind       string            `json:"kind"`
+	ID         string            `json:"id"`
+	Account    string            `json:"account,omitempty"` // AWS account, Azure subscription/resource group or GCP project
+	Name       string            `json:"name,omitempty"`
+	Region     string            `json:"region,omitempty"`
+	Zone       string            `json:"zone,omitempty"`
+	Size       string            `json:"size,omitempty"` // instance type, VM size or machine type
+	State      string            `json:"state,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	DiskGB     int               `json:"diskGB,omitempty"`
+	VolumeType string            `json:"volumeType,omitempty"`
+	Encrypted  *bool             `json:"encrypted,omitempty"`
+	AttachedTo []string          `json:"attachedTo,omitempty"` // volumes: instances using the volume
+	Volumes    []string          `json:"volumes,omitempty"`    // instances: attached volume IDs
+	PublicIP   string            `json:"publicIP,omitempty"`
+	Spot       bool              `json:"spot,omitempty"`
+	LaunchTime *time.Time        `json:"launchTime,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// Finding is a deterministic observation about a resource
+type Finding struct {
+	Severity   string `json:"severity"` // "high", "medium" or "low"
+	Category   string `json:"category"` // "cost", "security" or "operations"
+	ResourceID string `json:"resourceId"`
+	Message    string `json:"message"`
+}
+
+// LoadFile reads a provider export file and normalizes it into resources.
+// The format is detected from the document shape:
+//   - aws ec2 describe-instances --output json
+//   - aws ec2 describe-volumes --output json
+//   - az vm list [-d] --output json
+//   - gcloud compute instances list --format=json
+func LoadFile(path string) ([]Resource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory file: %v", err)
+	}
+
+	resources, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return resources, nil
+}
+
+// Parse detects the export format of data and normalizes it into resources
+func Parse(data []byte) ([]Resource, error) {
+	var probe interface{}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	switch doc := probe.(type) {
+	case map[string]interface{}:
+		if _, ok := doc["Reservations"]; ok {
+			return parseAWSInstances(data)
+		}
+		if _, ok := doc["Volumes"]; ok {
+			return parseAWSVolumes(data)
+		}
+	case []interface{}:
+		if len(doc) == 0 {
+			return nil, nil
+		}
+		first, _ := doc[0].(map[string]interface{})
+		if _, ok := first["hardwareProfile"]; ok {
+			return parseAzureVMs(data)
+		}
+		if _, ok := first["machineType"]; ok {
+			return parseGCPInstances(data)
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized export format; expected AWS describe-instances/describe-volumes, az vm list or gcloud compute instances list JSON")
+}
+
+// FilterProviders keeps only resources from the given providers. An empty list keeps everything.
+func FilterProviders(resources []Resource, providers []string) []Resource {
+	if len(providers) == 0 {
+		return resources
+	}
+
+	enabled := make(map[string]bool)
+	for _, p := range providers {
+		enabled[strings.ToLower(p)] = true
+	}
+
+	var filtered []Resource
+	for _, r := range resources {
+		if enabled[r.Provider] {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// Summary counts resources per provider/kind and state
+func Summary(resources []Resource) map[string]map[string]int {
+	counts := make(map[string]map[string]int)
+	for _, r := range resources {
+		key := r.Provider + "/" + r.Kind
+		if counts[key] == nil {
+			counts[key] = make(map[string]int)
+		}
+		counts[key][r.State]++
+	}
+	return counts
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// lastSegment returns the final path element of a provider resource URL or ID
+func lastSegment(s string) string {
+	if i := strings.LastIndexByte(s, '/'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
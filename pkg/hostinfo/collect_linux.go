@@ -0,0 +1,210 @@
+//go:build linux
+
+package hostinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cpuSampleInterval is the delay between the two /proc/stat reads used to compute CPU usage
+const cpuSampleInterval = 500 * time.Millisecond
+
+// Collect gathers a HostInfo snapshot from the local host using /proc, /sys and statfs
+func Collect() (*HostInfo, error) {
+	info := &HostInfo{CollectedAt: time.Now().UTC()}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hostname: %v", err)
+	}
+	info.Hostname = hostname
+
+	before, err := readCPUTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	info.OS = collectOS()
+
+	cpuinfo, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CPU info: %v", err)
+	}
+	info.CPU = parseCPUInfo(cpuinfo)
+
+	meminfo, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory info: %v", err)
+	}
+	info.Memory = parseMeminfo(meminfo)
+
+	loadavg, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read load average: %v", err)
+	}
+	if info.Load, err = parseLoadavg(loadavg); err != nil {
+		return nil, err
+	}
+	info.Load.LoadPerThread = loadPerThread(info.Load, info.CPU)
+
+	if uptime, err := os.ReadFile("/proc/uptime"); err == nil {
+		info.Uptime = parseUptime(uptime)
+	}
+
+	info.Disks = collectDisks()
+	info.Filesystems = collectFilesystems()
+	info.TopProcesses = collectProcesses()
+	info.OpenPorts = collectPorts()
+
+	// Sample CPU counters again now that the rest of the collection has given them time to move
+	if elapsed := time.Since(info.CollectedAt); elapsed < cpuSampleInterval {
+		time.Sleep(cpuSampleInterval - elapsed)
+	}
+	after, err := readCPUTimes()
+	if err != nil {
+		return nil, err
+	}
+	cpuUsage(&info.CPU, before, after)
+
+	return info, nil
+}
+
+func readCPUTimes() (cpuTimes, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, fmt.Errorf("failed to read CPU statistics: %v", err)
+	}
+	return parseCPUStat(data)
+}
+
+func collectOS() OSInfo {
+	var info OSInfo
+
+	if data, err := os.ReadFile("/etc/os-release"); err == nil {
+		info.Name, info.Version = parseOSRelease(data)
+	}
+
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err == nil {
+		info.KernelRelease = utsString(uts.Release[:])
+		info.KernelVersion = utsString(uts.Version[:])
+		info.Architecture = utsString(uts.Machine[:])
+	}
+
+	return info
+}
+
+func collectDisks() []Disk {
+	entries, err := os.ReadDir("/sys/block")
+	if err != nil {
+		return nil
+	}
+
+	var disks []Disk
+	for _, entry := range entries {
+		name := entry.Name()
+		if skipDisk(name) {
+			continue
+		}
+		base := filepath.Join("/sys/block", name)
+
+		sectors, _ := strconv.ParseUint(readTrimmed(filepath.Join(base, "size")), 10, 64)
+		if sectors == 0 {
+			continue
+		}
+
+		disk := newDisk(name, sectors,
+			readTrimmed(filepath.Join(base, "queue", "rotational")),
+			readTrimmed(filepath.Join(base, "device", "model")),
+			readTrimmed(filepath.Join(base, "queue", "scheduler")))
+		disks = append(disks, disk)
+	}
+
+	return disks
+}
+
+func collectFilesystems() []Filesystem {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+
+	var filesystems []Filesystem
+	for _, m := range parseMounts(data) {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(m.point, &st); err != nil || st.Blocks == 0 {
+			continue
+		}
+
+		fs := newFilesystem(m, st.Blocks, st.Bfree, st.Bavail, uint64(st.Bsize), st.Files, st.Ffree)
+		filesystems = append(filesystems, fs)
+	}
+
+	return filesystems
+}
+
+func collectProcesses() []Process {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	pageSize := os.Getpagesize()
+	var procs []Process
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		// Processes can exit between listing and reading, so errors are skipped
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		proc, err := parseProcStat(string(data), pageSize)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, proc)
+	}
+
+	return topProcesses(procs)
+}
+
+func collectPorts() []Port {
+	var ports []Port
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		data, err := os.ReadFile(filepath.Join("/proc/net", protocol))
+		if err != nil {
+			continue
+		}
+		ports = append(ports, parseNetSockets(data, protocol)...)
+	}
+	return dedupePorts(ports)
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// utsString converts a NUL-terminated utsname field into a string. The element
+// type of the array differs between architectures, hence the generic helper.
+func utsString[T int8 | uint8](field []T) string {
+	var b strings.Builder
+	for _, c := range field {
+		if c == 0 {
+			break
+		}
+		b.WriteByte(byte(c))
+	}
+	return b.String()
+}
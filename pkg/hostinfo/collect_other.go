@@ -0,0 +1,13 @@
+//go:build !linux
+
+package hostinfo
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Collect gathers a HostInfo snapshot from the local host. Only Linux is supported.
+func Collect() (*HostInfo, error) {
+	return nil, fmt.Errorf("local VM scanning is not supported on %s", runtime.GOOS)
+}
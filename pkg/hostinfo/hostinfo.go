@@ -0,0 +1,113 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HostInfo is a point-in-time snapshot of a host's configuration and utilization
+type HostInfo struct {
+	Hostname     string       `json:"hostname"`
+	CollectedAt  time.Time    `json:"collectedAt"`
+	Uptime       string       `json:"uptime"`
+	OS           OSInfo       `json:"os"`
+	CPU          CPUInfo      `json:"cpu"`
+	Memory       MemoryInfo   `json:"memory"`
+	Load         LoadInfo     `json:"load"`
+	Disks        []Disk       `json:"disks"`
+	Filesystems  []Filesystem `json:"filesystems"`
+	TopProcesses []Process    `json:"topProcesses"`
+	OpenPorts    []Port       `json:"openPorts"`
+}
+
+type OSInfo struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	KernelRelease string `json:"kernelRelease"`
+	KernelVersion string `json:"kernelVersion"`
+	Architecture  string `json:"architecture"`
+}
+
+type CPUInfo struct {
+	Model        string  `json:"model"`
+	Sockets      int     `json:"sockets"`
+	Cores        int     `json:"cores"`
+	Threads      int     `json:"threads"`
+	MHz          float64 `json:"mhz"`
+	UsagePercent float64 `json:"usagePercent"`
+	IOWaitPct    float64 `json:"ioWaitPercent"`
+	StealPct     float64 `json:"stealPercent"`
+}
+
+type MemoryInfo struct {
+	TotalMB      uint64  `json:"totalMB"`
+	AvailableMB  uint64  `json:"availableMB"`
+	UsedPercent  float64 `json:"usedPercent"`
+	BuffersMB    uint64  `json:"buffersMB"`
+	CachedMB     uint64  `json:"cachedMB"`
+	SwapTotalMB  uint64  `json:"swapTotalMB"`
+	SwapFreeMB   uint64  `json:"swapFreeMB"`
+	HugePagesTot uint64  `json:"hugePagesTotal"`
+}
+
+type LoadInfo struct {
+	Load1         float64 `json:"load1"`
+	Load5         float64 `json:"load5"`
+	Load15        float64 `json:"load15"`
+	RunningProcs  int     `json:"runningProcesses"`
+	TotalProcs    int     `json:"totalProcesses"`
+	LoadPerThread float64 `json:"load1PerThread"`
+}
+
+// Disk describes a block device as reported by /sys/block
+type Disk struct {
+	Name       string  `json:"name"`
+	SizeGB     float64 `json:"sizeGB"`
+	Rotational bool    `json:"rotational"`
+	Model      string  `json:"model,omitempty"`
+	Scheduler  string  `json:"scheduler,omitempty"`
+}
+
+// Filesystem describes a mounted filesystem and its usage
+type Filesystem struct {
+	Device        string  `json:"device"`
+	MountPoint    string  `json:"mountPoint"`
+	Type          string  `json:"type"`
+	SizeGB        float64 `json:"sizeGB"`
+	UsedPercent   float64 `json:"usedPercent"`
+	InodesUsedPct float64 `json:"inodesUsedPercent"`
+	MountOptions  string  `json:"mountOptions,omitempty"`
+}
+
+// Process is a summary of a single running process
+type Process struct {
+	PID        int     `json:"pid"`
+	Name       string  `json:"name"`
+	State      string  `json:"state"`
+	RSSMB      float64 `json:"rssMB"`
+	CPUSeconds float64 `json:"cpuSeconds"`
+	Threads    int     `json:"threads"`
+}
+
+// Port is a listening TCP socket or bound UDP socket
+type Port struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+}
+
+// ToMap converts the snapshot into the generic map consumed by scanner.ScanVirtualMachine
+func (h *HostInfo) ToMap() (map[string]interface{}, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal host info: %v", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to convert host info: %v", err)
+	}
+
+	return m, nil
+}
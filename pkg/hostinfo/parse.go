@@ -0,0 +1,426 @@
+package hostinfo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, which is 100 on every mainstream Linux architecture
+const clockTicks = 100
+
+// maxTopProcesses is the number of processes kept in HostInfo.TopProcesses
+const maxTopProcesses = 10
+
+// pseudoFilesystems are mount types that don't represent real storage
+var pseudoFilesystems = map[string]bool{
+	"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "tmpfs": true,
+	"cgroup": true, "cgroup2": true, "pstore": true, "bpf": true, "tracefs": true,
+	"debugfs": true, "securityfs": true, "configfs": true, "fusectl": true,
+	"mqueue": true, "hugetlbfs": true, "autofs": true, "binfmt_misc": true,
+	"rpc_pipefs": true, "nsfs": true, "overlay": true, "squashfs": true,
+	"efivarfs": true, "ramfs": true, "fuse.lxcfs": true, "fuse.gvfsd-fuse": true,
+}
+
+// cpuTimes holds the aggregate jiffy counters from the first line of /proc/stat
+type cpuTimes struct {
+	total  uint64
+	idle   uint64
+	iowait uint64
+	steal  uint64
+}
+
+// mount is a single entry from /proc/mounts
+type mount struct {
+	device  string
+	point   string
+	fsType  string
+	options string
+}
+
+func parseOSRelease(data []byte) (name, version string) {
+	values := parseKeyValues(data, "=")
+	name = strings.Trim(values["PRETTY_NAME"], `"`)
+	if name == "" {
+		name = strings.Trim(values["NAME"], `"`)
+	}
+	version = strings.Trim(values["VERSION_ID"], `"`)
+	return name, version
+}
+
+func parseCPUInfo(data []byte) CPUInfo {
+	var info CPUInfo
+	sockets := make(map[string]bool)
+	cores := make(map[string]bool)
+	var physicalID string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "processor":
+			info.Threads++
+		case "model name", "Model", "cpu model":
+			if info.Model == "" {
+				info.Model = value
+			}
+		case "cpu MHz":
+			if info.MHz == 0 {
+				info.MHz, _ = strconv.ParseFloat(value, 64)
+			}
+		case "physical id":
+			physicalID = value
+			sockets[value] = true
+		case "core id":
+			cores[physicalID+"/"+value] = true
+		}
+	}
+
+	info.Sockets = len(sockets)
+	info.Cores = len(cores)
+	// Virtualized or ARM hosts often omit topology fields
+	if info.Sockets == 0 {
+		info.Sockets = 1
+	}
+	if info.Cores == 0 {
+		info.Cores = info.Threads
+	}
+
+	return info
+}
+
+func parseCPUStat(data []byte) (cpuTimes, error) {
+	line, _, _ := strings.Cut(string(data), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuTimes{}, fmt.Errorf("unexpected /proc/stat format")
+	}
+
+	var times cpuTimes
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return cpuTimes{}, fmt.Errorf("invalid /proc/stat value %q: %v", field, err)
+		}
+		// guest and guest_nice are already accounted for in user and nice
+		if i < 8 {
+			times.total += value
+		}
+		switch i {
+		case 3:
+			times.idle += value
+		case 4:
+			times.iowait = value
+			times.idle += value
+		case 7:
+			times.steal = value
+		}
+	}
+
+	return times, nil
+}
+
+// cpuUsage fills in the utilization fields of info from two /proc/stat samples
+func cpuUsage(info *CPUInfo, before, after cpuTimes) {
+	total := float64(after.total - before.total)
+	if total <= 0 {
+		return
+	}
+	info.UsagePercent = round(100 * (1 - float64(after.idle-before.idle)/total))
+	info.IOWaitPct = round(100 * float64(after.iowait-before.iowait) / total)
+	info.StealPct = round(100 * float64(after.steal-before.steal) / total)
+}
+
+func parseMeminfo(data []byte) MemoryInfo {
+	values := parseKeyValues(data, ":")
+	kb := func(key string) uint64 {
+		value, _ := strconv.ParseUint(strings.TrimSuffix(values[key], " kB"), 10, 64)
+		return value
+	}
+
+	info := MemoryInfo{
+		TotalMB:      kb("MemTotal") / 1024,
+		AvailableMB:  kb("MemAvailable") / 1024,
+		BuffersMB:    kb("Buffers") / 1024,
+		CachedMB:     kb("Cached") / 1024,
+		SwapTotalMB:  kb("SwapTotal") / 1024,
+		SwapFreeMB:   kb("SwapFree") / 1024,
+		HugePagesTot: kb("HugePages_Total"),
+	}
+	// Kernels before 3.14 and some containers don't report MemAvailable
+	if _, ok := values["MemAvailable"]; !ok {
+		info.AvailableMB = (kb("MemFree") + kb("Buffers") + kb("Cached")) / 1024
+	}
+	if info.AvailableMB > info.TotalMB {
+		info.AvailableMB = info.TotalMB
+	}
+	if info.TotalMB > 0 {
+		info.UsedPercent = round(100 * float64(info.TotalMB-info.AvailableMB) / float64(info.TotalMB))
+	}
+
+	return info
+}
+
+func parseLoadavg(data []byte) (LoadInfo, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) < 4 {
+		return LoadInfo{}, fmt.Errorf("unexpected /proc/loadavg format")
+	}
+
+	var info LoadInfo
+	info.Load1, _ = strconv.ParseFloat(fields[0], 64)
+	info.Load5, _ = strconv.ParseFloat(fields[1], 64)
+	info.Load15, _ = strconv.ParseFloat(fields[2], 64)
+	if running, total, ok := strings.Cut(fields[3], "/"); ok {
+		info.RunningProcs, _ = strconv.Atoi(running)
+		info.TotalProcs, _ = strconv.Atoi(total)
+	}
+
+	return info, nil
+}
+
+func loadPerThread(load LoadInfo, cpu CPUInfo) float64 {
+	if cpu.Threads == 0 {
+		return 0
+	}
+	return round(load.Load1 / float64(cpu.Threads))
+}
+
+func parseUptime(data []byte) string {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return ""
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return ""
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+func parseMounts(data []byte) []mount {
+	var mounts []mount
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || pseudoFilesystems[fields[2]] || seen[fields[1]] {
+			continue
+		}
+		seen[fields[1]] = true
+		mounts = append(mounts, mount{
+			device:  fields[0],
+			point:   unescapeMount(fields[1]),
+			fsType:  fields[2],
+			options: fields[3],
+		})
+	}
+
+	return mounts
+}
+
+// newFilesystem builds a Filesystem from a mount entry and its statfs counters
+func newFilesystem(m mount, blocks, bfree, bavail, bsize, files, ffree uint64) Filesystem {
+	fs := Filesystem{
+		Device:       m.device,
+		MountPoint:   m.point,
+		Type:         m.fsType,
+		SizeGB:       round(float64(blocks*bsize) / (1 << 30)),
+		MountOptions: m.options,
+	}
+	// Usage is relative to the space available to unprivileged users, matching df
+	if usable := blocks - bfree + bavail; usable > 0 {
+		fs.UsedPercent = round(100 * float64(blocks-bfree) / float64(usable))
+	}
+	if files > 0 {
+		fs.InodesUsedPct = round(100 * float64(files-ffree) / float64(files))
+	}
+	return fs
+}
+
+// skipDisk reports whether a block device is virtual and not worth reporting
+func skipDisk(name string) bool {
+	return strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") || strings.HasPrefix(name, "zram")
+}
+
+// newDisk builds a Disk from the raw /sys/block attribute values
+func newDisk(name string, sectors uint64, rotational, model, scheduler string) Disk {
+	// The active scheduler is shown in brackets, e.g. "[mq-deadline] none"
+	if start := strings.IndexByte(scheduler, '['); start >= 0 {
+		if end := strings.IndexByte(scheduler[start:], ']'); end > 0 {
+			scheduler = scheduler[start+1 : start+end]
+		}
+	}
+
+	// size is always reported in 512-byte sectors
+	return Disk{
+		Name:       name,
+		SizeGB:     round(float64(sectors*512) / (1 << 30)),
+		Rotational: rotational == "1",
+		Model:      model,
+		Scheduler:  scheduler,
+	}
+}
+
+// unescapeMount decodes the octal escapes (\040 for space, etc.) used in /proc/mounts
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseProcStat parses a /proc/[pid]/stat line. pageSize is used to convert RSS pages into bytes.
+func parseProcStat(line string, pageSize int) (Process, error) {
+	// The command name is wrapped in parentheses and may itself contain spaces or parentheses
+	open := strings.IndexByte(line, '(')
+	close := strings.LastIndexByte(line, ')')
+	if open < 0 || close < open {
+		return Process{}, fmt.Errorf("unexpected stat format")
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(line[:open]))
+	if err != nil {
+		return Process{}, fmt.Errorf("invalid pid: %v", err)
+	}
+
+	// Fields after the command name start at field 3 (state)
+	fields := strings.Fields(line[close+1:])
+	if len(fields) < 22 {
+		return Process{}, fmt.Errorf("unexpected stat format")
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	rssPages, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	return Process{
+		PID:        pid,
+		Name:       line[open+1 : close],
+		State:      fields[0],
+		RSSMB:      round(float64(rssPages*int64(pageSize)) / (1024 * 1024)),
+		CPUSeconds: round(float64(utime+stime) / clockTicks),
+		Threads:    threads,
+	}, nil
+}
+
+// topProcesses returns the processes using the most memory, breaking ties on CPU time
+func topProcesses(procs []Process) []Process {
+	sort.Slice(procs, func(i, j int) bool {
+		if procs[i].RSSMB != procs[j].RSSMB {
+			return procs[i].RSSMB > procs[j].RSSMB
+		}
+		return procs[i].CPUSeconds > procs[j].CPUSeconds
+	})
+	if len(procs) > maxTopProcesses {
+		procs = procs[:maxTopProcesses]
+	}
+	return procs
+}
+
+// parseNetSockets parses /proc/net/{tcp,tcp6,udp,udp6} and returns listening sockets
+func parseNetSockets(data []byte, protocol string) []Port {
+	// TCP_LISTEN is 0A; unconnected UDP sockets report TCP_CLOSE (07)
+	wantState := "0A"
+	if strings.HasPrefix(protocol, "udp") {
+		wantState = "07"
+	}
+
+	var ports []Port
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != wantState {
+			continue
+		}
+		addrHex, portHex, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(portHex, 16, 16)
+		if err != nil {
+			continue
+		}
+		ports = append(ports, Port{
+			Protocol: protocol,
+			Address:  decodeProcAddr(addrHex),
+			Port:     int(port),
+		})
+	}
+
+	return ports
+}
+
+// decodeProcAddr decodes the hex address format of /proc/net/*, which stores
+// each 32-bit word in host (little-endian) byte order
+func decodeProcAddr(s string) string {
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw)%4 != 0 {
+		return s
+	}
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return net.IP(raw).String()
+}
+
+// dedupePorts removes duplicates and sorts ports by protocol and number
+func dedupePorts(ports []Port) []Port {
+	seen := make(map[Port]bool)
+	var result []Port
+	for _, p := range ports {
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Protocol != result[j].Protocol {
+			return result[i].Protocol < result[j].Protocol
+		}
+		return result[i].Port < result[j].Port
+	})
+	return result
+}
+
+func parseKeyValues(data []byte, sep string) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), sep)
+		if ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+func round(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
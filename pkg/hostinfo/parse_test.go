@@ -0,0 +1,311 @@
+package hostinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMeminfo(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want MemoryInfo
+	}{
+		{
+			name: "MemAvailable",
+			data: "MemTotal:        8192000 kB\nMemFree:          512000 kB\nMemAvailable:    2048000 kB\n" +
+				"Buffers:          102400 kB\nCached:          1024000 kB\nSwapTotal:       2048000 kB\nSwapFree:        1024000 kB\nHugePages_Total:       4\n",
+			want: MemoryInfo{TotalMB: 8000, AvailableMB: 2000, UsedPercent: 75, BuffersMB: 100, CachedMB: 1000,
+				SwapTotalMB: 2000, SwapFreeMB: 1000, HugePagesTot: 4},
+		},
+		{
+			name: "no MemAvailable",
+			data: "MemTotal:        4096000 kB\nMemFree:          512000 kB\nBuffers:          512000 kB\nCached:          1024000 kB\n",
+			want: MemoryInfo{TotalMB: 4000, AvailableMB: 2000, UsedPercent: 50, BuffersMB: 500, CachedMB: 1000},
+		},
+		{
+			name: "available capped at total",
+			data: "MemTotal:        1024000 kB\nMemFree:         1024000 kB\nCached:          1024000 kB\n",
+			want: MemoryInfo{TotalMB: 1000, AvailableMB: 1000, CachedMB: 1000},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMeminfo([]byte(tt.data)); got != tt.want {
+				t.Errorf("parseMeminfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLoadavg(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    LoadInfo
+		wantErr bool
+	}{
+		{"0.52 0.58 0.59 2/1024 12345\n", LoadInfo{Load1: 0.52, Load5: 0.58, Load15: 0.59, RunningProcs: 2, TotalProcs: 1024}, false},
+		{"1.00 2.00 3.00 bad 1", LoadInfo{Load1: 1, Load5: 2, Load15: 3}, false},
+		{"0.52 0.58", LoadInfo{}, true},
+		{"", LoadInfo{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLoadavg([]byte(tt.data))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseLoadavg(%q) = %+v, %v, want %+v, error %v", tt.data, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseCPUStat(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    cpuTimes
+		wantErr bool
+	}{
+		// user nice system idle iowait irq softirq steal guest guest_nice
+		{"cpu  100 0 50 800 20 5 5 10 30 0\ncpu0 50 0 25 400 10 2 3 5 15 0\n", cpuTimes{total: 990, idle: 820, iowait: 20, steal: 10}, false},
+		{"cpu  100 0 50 800\n", cpuTimes{total: 950, idle: 800}, false},
+		{"cpu0 100 0 50 800 20\n", cpuTimes{}, true},
+		{"cpu  100 x 50 800 20\n", cpuTimes{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUStat([]byte(tt.data))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseCPUStat(%q) = %+v, %v, want %+v, error %v", tt.data, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCPUUsage(t *testing.T) {
+	var info CPUInfo
+	cpuUsage(&info, cpuTimes{total: 1000, idle: 800, iowait: 50, steal: 10}, cpuTimes{total: 2000, idle: 1500, iowait: 100, steal: 30})
+	want := CPUInfo{UsagePercent: 30, IOWaitPct: 5, StealPct: 2}
+	if info != want {
+		t.Errorf("cpuUsage() = %+v, want %+v", info, want)
+	}
+}
+
+func TestParseCPUInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want CPUInfo
+	}{
+		{
+			name: "two sockets with hyperthreads",
+			data: "processor\t: 0\nmodel name\t: Intel(R) Xeon(R)\ncpu MHz\t\t: 2500.000\nphysical id\t: 0\ncore id\t\t: 0\n\n" +
+				"processor\t: 1\nmodel name\t: Intel(R) Xeon(R)\nphysical id\t: 0\ncore id\t\t: 0\n\n" +
+				"processor\t: 2\nmodel name\t: Intel(R) Xeon(R)\nphysical id\t: 1\ncore id\t\t: 0\n\n" +
+				"processor\t: 3\nmodel name\t: Intel(R) Xeon(R)\nphysical id\t: 1\ncore id\t\t: 0\n",
+			want: CPUInfo{Model: "Intel(R) Xeon(R)", Sockets: 2, Cores: 2, Threads: 4, MHz: 2500},
+		},
+		{
+			name: "no topology",
+			data: "processor\t: 0\nModel\t\t: Raspberry Pi 4\n\nprocessor\t: 1\n",
+			want: CPUInfo{Model: "Raspberry Pi 4", Sockets: 1, Cores: 2, Threads: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCPUInfo([]byte(tt.data)); got != tt.want {
+				t.Errorf("parseCPUInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		data    string
+		name    string
+		version string
+	}{
+		{"NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\n", "Ubuntu 22.04.4 LTS", "22.04"},
+		{"NAME=\"Alpine Linux\"\nVERSION_ID=3.19.1\n", "Alpine Linux", "3.19.1"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		name, version := parseOSRelease([]byte(tt.data))
+		if name != tt.name || version != tt.version {
+			t.Errorf("parseOSRelease(%q) = %q, %q, want %q, %q", tt.data, name, version, tt.name, tt.version)
+		}
+	}
+}
+
+func TestParseUptime(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"93784.12 180000.50\n", "26h3m4s"},
+		{"59.99 10.00", "59s"},
+		{"", ""},
+		{"invalid", ""},
+	}
+	for _, tt := range tests {
+		if got := parseUptime([]byte(tt.data)); got != tt.want {
+			t.Errorf("parseUptime(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	data := "sysfs /sys sysfs rw,nosuid 0 0\n" +
+		"/dev/nvme0n1p1 / ext4 rw,relatime 0 0\n" +
+		"tmpfs /run tmpfs rw,nosuid 0 0\n" +
+		"/dev/sdb1 /mnt/backup\\040disk xfs rw,noatime 0 0\n" +
+		"/dev/nvme0n1p1 / ext4 rw,relatime 0 0\n" +
+		"short line\n"
+
+	want := []mount{
+		{device: "/dev/nvme0n1p1", point: "/", fsType: "ext4", options: "rw,relatime"},
+		{device: "/dev/sdb1", point: "/mnt/backup disk", fsType: "xfs", options: "rw,noatime"},
+	}
+	if got := parseMounts([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMounts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnescapeMount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/data", "/data"},
+		{`/mnt/my\040disk`, "/mnt/my disk"},
+		{`/mnt/tab\011and\134slash`, "/mnt/tab\tand\\slash"},
+		{`/mnt/trailing\04`, `/mnt/trailing\04`},
+		{`/mnt/not\999octal`, `/mnt/not\999octal`},
+	}
+	for _, tt := range tests {
+		if got := unescapeMount(tt.in); got != tt.want {
+			t.Errorf("unescapeMount(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewFilesystem(t *testing.T) {
+	m := mount{device: "/dev/sda1", point: "/", fsType: "ext4", options: "rw"}
+	// 100 GiB of 4 KiB blocks, 40% free of which 5% is reserved for root
+	blocks := uint64(100 << 30 / 4096)
+	got := newFilesystem(m, blocks, blocks*40/100, blocks*35/100, 4096, 1000, 250)
+	want := Filesystem{Device: "/dev/sda1", MountPoint: "/", Type: "ext4", SizeGB: 100, UsedPercent: 63.16, InodesUsedPct: 75, MountOptions: "rw"}
+	if got != want {
+		t.Errorf("newFilesystem() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewDisk(t *testing.T) {
+	tests := []struct {
+		scheduler string
+		want      string
+	}{
+		{"[mq-deadline] kyber none", "mq-deadline"},
+		{"mq-deadline [none]", "none"},
+		{"none", "none"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		disk := newDisk("sda", 2*1024*1024*1024/512*10, "1", "Samsung SSD", tt.scheduler)
+		if disk.Scheduler != tt.want || disk.SizeGB != 20 || !disk.Rotational {
+			t.Errorf("newDisk(scheduler %q) = %+v, want scheduler %q, 20 GB, rotational", tt.scheduler, disk, tt.want)
+		}
+	}
+
+	for name, skip := range map[string]bool{"loop0": true, "ram1": true, "zram0": true, "sda": false, "nvme0n1": false} {
+		if skipDisk(name) != skip {
+			t.Errorf("skipDisk(%q) = %v, want %v", name, !skip, skip)
+		}
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Process
+		wantErr bool
+	}{
+		{
+			name: "plain",
+			line: "1234 (nginx) S 1 1234 1234 0 -1 4194560 500 0 0 0 250 150 0 0 20 0 4 0 100 104857600 25600 18446744073709551615",
+			want: Process{PID: 1234, Name: "nginx", State: "S", RSSMB: 100, CPUSeconds: 4, Threads: 4},
+		},
+		{
+			name: "name with spaces and parentheses",
+			line: "42 (tmux: server (1)) R 1 42 42 0 -1 0 0 0 0 0 100 0 0 0 20 0 1 0 100 0 256 0",
+			want: Process{PID: 42, Name: "tmux: server (1)", State: "R", RSSMB: 1, CPUSeconds: 1, Threads: 1},
+		},
+		{name: "truncated", line: "42 (bash) S 1 42", wantErr: true},
+		{name: "no name", line: "42 bash S", wantErr: true},
+		{name: "bad pid", line: "x (bash) S 1 42 42 0 -1 0 0 0 0 0 100 0 0 0 20 0 1 0 100 0 256 0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProcStat(tt.line, 4096)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseProcStat() = %+v, %v, want %+v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTopProcesses(t *testing.T) {
+	procs := []Process{{PID: 1, RSSMB: 10}, {PID: 2, RSSMB: 50}, {PID: 3, RSSMB: 10, CPUSeconds: 5}}
+	for i := 4; i <= 15; i++ {
+		procs = append(procs, Process{PID: i, RSSMB: 1})
+	}
+
+	top := topProcesses(procs)
+	if len(top) != maxTopProcesses {
+		t.Fatalf("topProcesses() returned %d processes, want %d", len(top), maxTopProcesses)
+	}
+	if got := []int{top[0].PID, top[1].PID, top[2].PID}; !reflect.DeepEqual(got, []int{2, 3, 1}) {
+		t.Errorf("topProcesses() starts with PIDs %v, want [2 3 1]", got)
+	}
+}
+
+func TestParseNetSockets(t *testing.T) {
+	tcp := "  sl  local_address rem_address   st tx_queue rx_queue\n" +
+		"   0: 00000000:0016 00000000:0000 0A 00000000:00000000\n" +
+		"   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000\n" +
+		"   2: 0100007F:A1B2 0100007F:1F90 01 00000000:00000000\n"
+	udp6 := "  sl  local_address rem_address   st\n" +
+		"   0: 00000000000000000000000001000000:0035 00000000000000000000000000000000:0000 07\n"
+
+	tests := []struct {
+		data     string
+		protocol string
+		want     []Port
+	}{
+		{tcp, "tcp", []Port{{Protocol: "tcp", Address: "0.0.0.0", Port: 22}, {Protocol: "tcp", Address: "127.0.0.1", Port: 8080}}},
+		{udp6, "udp6", []Port{{Protocol: "udp6", Address: "::1", Port: 53}}},
+		{"header only\n", "tcp", nil},
+	}
+	for _, tt := range tests {
+		if got := parseNetSockets([]byte(tt.data), tt.protocol); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseNetSockets(%s) = %+v, want %+v", tt.protocol, got, tt.want)
+		}
+	}
+}
+
+func TestDedupePorts(t *testing.T) {
+	ports := []Port{
+		{Protocol: "udp", Address: "0.0.0.0", Port: 53},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 443},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 22},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 443},
+	}
+	want := []Port{
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 22},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 443},
+		{Protocol: "udp", Address: "0.0.0.0", Port: 53},
+	}
+	if got := dedupePorts(ports); !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupePorts() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package hostinfo
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sectionMarker prefixes the header line of each section printed by collectScript
+const sectionMarker = "@@cloudigest:"
+
+// collectScript gathers the raw host facts in a single SSH round trip. It only
+// relies on POSIX sh, coreutils and procfs so it works on minimal images. Mount
+// points are printed escaped as in /proc/mounts, and decoded for stat by turning
+// the \NNN octal escapes into the \0NNN form that printf %b understands.
+const collectScript = `export LC_ALL=C
+section() { echo "@@cloudigest:$1"; }
+section stat1; head -n1 /proc/stat
+section hostname; hostname
+section os-release; cat /etc/os-release 2>/dev/null
+section uname; uname -r; uname -v; uname -m
+section cpuinfo; cat /proc/cpuinfo
+section meminfo; cat /proc/meminfo
+section loadavg; cat /proc/loadavg
+section uptime; cat /proc/uptime
+section pagesize; getconf PAGESIZE 2>/dev/null
+section disks
+for d in /sys/block/*; do
+  printf '%s|%s|%s|%s|%s\n' "${d##*/}" "$(cat $d/size 2>/dev/null)" "$(cat $d/queue/rotational 2>/dev/null)" \
+    "$(cat $d/device/model 2>/dev/null)" "$(cat $d/queue/scheduler 2>/dev/null)"
+done
+section mounts; cat /proc/mounts
+section statfs
+awk '{print $2}' /proc/mounts | while read -r m; do
+  p=$(printf '%s' "$m" | sed 's/\\\([0-7][0-7][0-7]\)/\\0\1/g')
+  printf '%s|' "$m"; stat -f -c '%b|%f|%a|%S|%c|%d' "$(printf '%b' "$p")" 2>/dev/null || echo
+done
+section procs; cat /proc/[0-9]*/stat 2>/dev/null
+for p in tcp tcp6 udp udp6; do section "net-$p"; cat /proc/net/$p 2>/dev/null; done
+sleep 0.5 2>/dev/null || sleep 1
+section stat2; head -n1 /proc/stat
+`
+
+// CollectRemote gathers a HostInfo snapshot from a remote Linux host over SSH
+func CollectRemote(target Target, opts SSHOptions) (*HostInfo, error) {
+	client, err := Dial(target, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return collectOverSSH(client)
+}
+
+func collectOverSSH(client *ssh.Client) (*HostInfo, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH session: %v", err)
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = strings.NewReader(collectScript)
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	// The script is fed through stdin so it runs under sh regardless of the login shell
+	collectedAt := time.Now().UTC()
+	if err := session.Run("sh -s"); err != nil {
+		return nil, fmt.Errorf("failed to run collection script: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseCollectOutput(stdout.Bytes(), collectedAt)
+}
+
+// parseCollectOutput builds a HostInfo from the output of collectScript
+func parseCollectOutput(output []byte, collectedAt time.Time) (*HostInfo, error) {
+	sections := splitSections(output)
+	info := &HostInfo{CollectedAt: collectedAt}
+
+	info.Hostname = strings.TrimSpace(string(sections["hostname"]))
+
+	info.OS.Name, info.OS.Version = parseOSRelease(sections["os-release"])
+	uname := strings.Split(strings.TrimSpace(string(sections["uname"])), "\n")
+	if len(uname) == 3 {
+		info.OS.KernelRelease = uname[0]
+		info.OS.KernelVersion = uname[1]
+		info.OS.Architecture = uname[2]
+	}
+
+	info.CPU = parseCPUInfo(sections["cpuinfo"])
+	info.Memory = parseMeminfo(sections["meminfo"])
+	if info.Memory.TotalMB == 0 {
+		return nil, fmt.Errorf("remote host did not report /proc/meminfo; only Linux hosts are supported")
+	}
+
+	var err error
+	if info.Load, err = parseLoadavg(sections["loadavg"]); err != nil {
+		return nil, err
+	}
+	info.Load.LoadPerThread = loadPerThread(info.Load, info.CPU)
+	info.Uptime = parseUptime(sections["uptime"])
+
+	for _, line := range lines(sections["disks"]) {
+		fields := strings.Split(line, "|")
+		if len(fields) != 5 || skipDisk(fields[0]) {
+			continue
+		}
+		sectors, _ := strconv.ParseUint(fields[1], 10, 64)
+		if sectors == 0 {
+			continue
+		}
+		info.Disks = append(info.Disks, newDisk(fields[0], sectors, fields[2], fields[3], fields[4]))
+	}
+
+	info.Filesystems = parseRemoteFilesystems(sections["mounts"], sections["statfs"])
+
+	pageSize, err := strconv.Atoi(strings.TrimSpace(string(sections["pagesize"])))
+	if err != nil || pageSize <= 0 {
+		pageSize = 4096
+	}
+	var procs []Process
+	for _, line := range lines(sections["procs"]) {
+		if proc, err := parseProcStat(line, pageSize); err == nil {
+			procs = append(procs, proc)
+		}
+	}
+	info.TopProcesses = topProcesses(procs)
+
+	var ports []Port
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		ports = append(ports, parseNetSockets(sections["net-"+protocol], protocol)...)
+	}
+	info.OpenPorts = dedupePorts(ports)
+
+	if before, err := parseCPUStat(sections["stat1"]); err == nil {
+		if after, err := parseCPUStat(sections["stat2"]); err == nil {
+			cpuUsage(&info.CPU, before, after)
+		}
+	}
+
+	return info, nil
+}
+
+// parseRemoteFilesystems joins /proc/mounts with the "mount|blocks|bfree|bavail|bsize|files|ffree"
+// lines produced by stat -f
+func parseRemoteFilesystems(mounts, statfs []byte) []Filesystem {
+	counters := make(map[string][]uint64)
+	for _, line := range lines(statfs) {
+		fields := strings.Split(line, "|")
+		if len(fields) != 7 {
+			continue
+		}
+		values := make([]uint64, 6)
+		for i, field := range fields[1:] {
+			values[i], _ = strconv.ParseUint(field, 10, 64)
+		}
+		counters[unescapeMount(fields[0])] = values
+	}
+
+	var filesystems []Filesystem
+	for _, m := range parseMounts(mounts) {
+		c, ok := counters[m.point]
+		if !ok || c[0] == 0 {
+			continue
+		}
+		filesystems = append(filesystems, newFilesystem(m, c[0], c[1], c[2], c[3], c[4], c[5]))
+	}
+
+	return filesystems
+}
+
+func splitSections(output []byte) map[string][]byte {
+	sections := make(map[string][]byte)
+	var name string
+	var current bytes.Buffer
+
+	flush := func() {
+		if name != "" {
+			sections[name] = append([]byte(nil), current.Bytes()...)
+		}
+		current.Reset()
+	}
+
+	for _, line := range bytes.SplitAfter(output, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte(sectionMarker)) {
+			flush()
+			name = strings.TrimSpace(string(line[len(sectionMarker):]))
+			continue
+		}
+		current.Write(line)
+	}
+	flush()
+
+	return sections
+}
+
+func lines(data []byte) []string {
+	var result []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+package hostinfo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultIdentityFiles are the private keys tried when no identity file is given
+var defaultIdentityFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// Target identifies a remote host reachable over SSH
+type Target struct {
+	User string
+	Host string
+	Port int
+}
+
+// SSHOptions controls how connections to remote hosts are authenticated
+type SSHOptions struct {
+	// IdentityFiles are private keys to offer. Defaults to the standard keys in ~/.ssh.
+	IdentityFiles []string
+	// KnownHostsFile is used to verify host keys. Defaults to ~/.ssh/known_hosts.
+	KnownHostsFile string
+	Timeout        time.Duration
+}
+
+// ParseTarget parses a [user@]host[:port] string. The current user and port 22 are used as defaults.
+func ParseTarget(s string) (Target, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Target{}, fmt.Errorf("empty SSH target")
+	}
+
+	target := Target{Port: 22}
+	if at := strings.LastIndexByte(s, '@'); at >= 0 {
+		target.User = s[:at]
+		s = s[at+1:]
+	}
+
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return Target{}, fmt.Errorf("invalid port in SSH target %q", s)
+		}
+		target.Host = host
+		target.Port = p
+	} else {
+		target.Host = strings.Trim(s, "[]")
+	}
+
+	if target.Host == "" {
+		return Target{}, fmt.Errorf("missing host in SSH target %q", s)
+	}
+
+	if target.User == "" {
+		current, err := user.Current()
+		if err != nil {
+			return Target{}, fmt.Errorf("no user given for %s and the current user is unknown: %v", target.Host, err)
+		}
+		target.User = current.Username
+	}
+
+	return target, nil
+}
+
+func (t Target) String() string {
+	if t.Port == 22 {
+		return t.User + "@" + t.Host
+	}
+	return t.User + "@" + net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// LoadInventory reads a hosts inventory file containing one [user@]host[:port]
+// per line. Blank lines and lines starting with # are ignored.
+func LoadInventory(path string) ([]Target, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory: %v", err)
+	}
+	defer file.Close()
+
+	var targets []Target
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		target, err := ParseTarget(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		targets = append(targets, target)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %v", err)
+	}
+
+	return targets, nil
+}
+
+// Dial opens an SSH connection to the target, authenticating with the SSH
+// agent (if SSH_AUTH_SOCK is set) and the configured private keys, and
+// verifying the host key against known_hosts.
+func Dial(target Target, opts SSHOptions) (*ssh.Client, error) {
+	home, _ := os.UserHomeDir()
+
+	knownHostsFile := opts.KnownHostsFile
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %v", err)
+	}
+
+	var authMethods []ssh.AuthMethod
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			// The agent is only needed to authenticate, which is over once Dial returns
+			defer conn.Close()
+			authMethods = append(authMethods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	signers, err := loadSigners(home, opts.IdentityFiles)
+	if err != nil {
+		return nil, err
+	}
+	if len(signers) > 0 {
+		authMethods = append(authMethods, ssh.PublicKeys(signers...))
+	}
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf("no SSH agent or usable private key found")
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 15 * time.Second
+	}
+
+	config := &ssh.ClientConfig{
+		User:            target.User,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(target.Port)), config)
+	if err != nil {
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return nil, fmt.Errorf("host key for %s is not in %s; connect once with ssh to verify it", target.Host, knownHostsFile)
+		}
+		return nil, fmt.Errorf("failed to connect to %s: %v", target, err)
+	}
+
+	return client, nil
+}
+
+// loadSigners parses the given private keys. When none are given, the default
+// keys in ~/.ssh are tried and missing or passphrase-protected ones are skipped.
+func loadSigners(home string, identityFiles []string) ([]ssh.Signer, error) {
+	explicit := len(identityFiles) > 0
+	if !explicit {
+		for _, name := range defaultIdentityFiles {
+			identityFiles = append(identityFiles, filepath.Join(home, ".ssh", name))
+		}
+	}
+
+	var signers []ssh.Signer
+	for _, path := range identityFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !explicit {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read identity file: %v", err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			var passErr *ssh.PassphraseMissingError
+			if !explicit || errors.As(err, &passErr) {
+				// Encrypted keys are expected to be served by the agent instead
+				continue
+			}
+			return nil, fmt.Errorf("failed to parse identity file %s: %v", path, err)
+		}
+		signers = append(signers, signer)
+	}
+
+	return signers, nil
+}
@@ -3,9 +3,12 @@ package image
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 
+	"cloudigest/pkg/pdf"
+
 	anthropic "github.com/liushuangls/go-anthropic/v2"
 	"github.com/sashabaranov/go-openai"
 )
@@ -232,3 +235,102 @@ func (a *Analyzer) GenerateRecommendations(imageAnalysis, docAnalysis string) (s
 		return resp.GetFirstContentText(), nil
 	}
 }
+
+// AnalyzeTerraform summarizes the deterministic findings and resource inventory of
+// Terraform code, a plan or state, and recommends fixes
+func (a *Analyzer) AnalyzeTerraform(summary map[string]interface{}) (string, error) {
+	summaryJSON, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal Terraform summary: %v", err)
+	}
+
+	systemPrompt := "You are an infrastructure-as-code expert reviewing Terraform. The findings were produced " +
+		"by deterministic checks and are accurate; explain their impact and provide remediation guidance. " +
+		"Always refer to resources by their Terraform address."
+	userPrompt := fmt.Sprintf("Please review this Terraform summary and provide:\n"+
+		"1. A prioritized explanation of the findings and how to fix each one in Terraform\n"+
+		"2. Security concerns beyond the automated findings\n"+
+		"3. Cost optimization opportunities\n"+
+		"4. Reliability and operational considerations\n"+
+		"5. Module structure and best-practice recommendations\n\n"+
+		"Terraform summary:\n%s", string(summaryJSON))
+
+	analysis, err := a.complete(systemPrompt, userPrompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze Terraform: %v", err)
+	}
+
+	return analysis, nil
+}
+
+// AnalyzePDF extracts the text of a PDF document and analyzes it like AnalyzeDocument,
+// citing page numbers for its findings
+func (a *Analyzer) AnalyzePDF(docPath string) (string, error) {
+	pages, err := pdf.ReadFile(docPath)
+	if err != nil {
+		return "", err
+	}
+
+	systemPrompt := "You are an infrastructure expert analyzing technical documentation. " +
+		"Extract key information about infrastructure requirements, design decisions, " +
+		"and provide optimization recommendations. The document text is split into pages marked " +
+		"[Page N]; cite the page each point comes from as (page N)."
+	userPrompt := fmt.Sprintf("Please analyze this technical document and provide insights about:\n"+
+		"1. Infrastructure requirements and dependencies\n"+
+		"2. Scalability and performance considerations\n"+
+		"3. Security requirements\n"+
+		"4. Operational considerations\n"+
+		"5. Recommendations for optimal deployment\n\n"+
+		"Document content:\n%s", pdf.Format(pages))
+
+	analysis, err := a.complete(systemPrompt, userPrompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze document: %v", err)
+	}
+
+	return analysis, nil
+}
+
+// complete sends a single-turn text prompt to whichever model the analyzer was created with
+func (a *Analyzer) complete(systemPrompt, userPrompt string) (string, error) {
+	if a.useOpenAI {
+		resp, err := a.openaiClient.CreateChatCompletion(
+			context.Background(),
+			openai.ChatCompletionRequest{
+				Model: openai.GPT4o,
+				Messages: []openai.ChatCompletionMessage{
+					{
+						Role:    openai.ChatMessageRoleSystem,
+						Content: systemPrompt,
+					},
+					{
+						Role:    openai.ChatMessageRoleUser,
+						Content: userPrompt,
+					},
+				},
+				MaxTokens: a.maxTokens,
+			},
+		)
+		if err != nil {
+			return "", err
+		}
+
+		return resp.Choices[0].Message.Content, nil
+	}
+
+	resp, err := a.claudeClient.CreateMessages(
+		context.Background(),
+		anthropic.MessagesRequest{
+			Model: anthropic.ModelClaude3Dot7SonnetLatest,
+			Messages: []anthropic.Message{
+				anthropic.NewUserTextMessage(systemPrompt + "\n\n" + userPrompt),
+			},
+			MaxTokens: a.maxTokens,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.GetFirstContentText(), nil
+}
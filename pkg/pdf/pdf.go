@@ -0,0 +1,258 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Page is the text of a single PDF page
+type Page struct {
+	Number int
+	Text   string
+}
+
+// pageMarker introduces each page in the text produced by Format
+var pageMarker = regexp.MustCompile(`^\[Page (\d+)\]$`)
+
+// ReadFile extracts the text of every page in a PDF file
+func ReadFile(path string) ([]Page, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %v", err)
+	}
+	return Extract(content)
+}
+
+// Extract extracts the text of every page of a PDF. Pages without extractable text,
+// such as scanned images, are left out.
+func Extract(content []byte) (pages []Page, err error) {
+	// The PDF library also panics on malformed xref tables, trailers and page trees
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %v", err)
+	}
+
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		text, err := pageText(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text from page %d: %v", i, err)
+		}
+
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.Join(strings.Fields(line), " "); line != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		if len(lines) > 0 {
+			pages = append(pages, Page{Number: i, Text: strings.Join(lines, "\n")})
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("PDF has no extractable text (it may be a scanned document)")
+	}
+
+	return pages, nil
+}
+
+// wordGap is the TJ adjustment, in thousandths of a text space unit, above which a
+// gap between glyphs is treated as a space between words
+const wordGap = 200
+
+// pageText interprets a page's content stream and returns its text. PDFs position text
+// rather than storing spaces and line breaks, so line breaks are inferred from text
+// moving to a new line, and spaces from gaps wider than a fraction of the font size
+// between where the previous text ended and where the next text starts.
+func pageText(page pdf.Page) (text string, err error) {
+	// The PDF library panics on malformed content streams
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed content: %v", r)
+		}
+	}()
+
+	// Font names are only unique within a page. Looking up font properties resolves
+	// PDF objects each time, so each font is read once.
+	fonts := make(map[string]*fontInfo)
+
+	var b strings.Builder
+	var font *fontInfo
+	var fontSize, charSpacing, wordSpacing, leading float64
+	scale := 1.0     // scale of the text matrix
+	var x, y float64 // start of the current line
+	lastX, lastY := 0.0, math.NaN()
+	var advance float64 // width of the text shown since the last move, in text space
+
+	show := func(raw string) {
+		text := raw
+		if font != nil {
+			text = font.enc.Decode(raw)
+			for i := 0; i < len(raw); i++ {
+				advance += font.width(int(raw[i]))/1000*fontSize + charSpacing
+				if raw[i] == ' ' {
+					advance += wordSpacing
+				}
+			}
+		}
+		b.WriteString(text)
+	}
+	// move continues text at a new position, breaking the line if it moved vertically
+	// and adding a space if it moved further right than the previous text reached
+	move := func() {
+		if !math.IsNaN(lastY) {
+			if math.Abs(y-lastY) > 0.5 {
+				b.WriteString("\n")
+			} else if gap := (x-lastX)/scale - advance; gap > 0.15*math.Max(fontSize, 1) {
+				b.WriteString(" ")
+			}
+		}
+		lastX, lastY = x, y
+		advance = 0
+	}
+
+	pdf.Interpret(page.V.Key("Contents"), func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+
+		switch op {
+		case "BT": // begin text, resetting the text matrix
+			x, y, scale = 0, 0, 1
+		case "Tf": // set font and size
+			font = nil
+			if len(args) == 2 {
+				name := args[0].Name()
+				if fonts[name] == nil {
+					fonts[name] = newFontInfo(page.Font(name))
+				}
+				font = fonts[name]
+				fontSize = args[1].Float64()
+			}
+		case "Tc": // character spacing
+			if len(args) == 1 {
+				charSpacing = args[0].Float64()
+			}
+		case "Tw": // word spacing
+			if len(args) == 1 {
+				wordSpacing = args[0].Float64()
+			}
+		case "TL": // leading
+			if len(args) == 1 {
+				leading = args[0].Float64()
+			}
+		case "Td", "TD": // move relative to the start of the current line
+			if len(args) == 2 {
+				if op == "TD" {
+					leading = -args[1].Float64()
+				}
+				x += args[0].Float64() * scale
+				y += args[1].Float64() * scale
+				move()
+			}
+		case "Tm": // set the text matrix
+			if len(args) == 6 {
+				if a := args[0].Float64(); a != 0 {
+					scale = math.Abs(a)
+				}
+				x, y = args[4].Float64(), args[5].Float64()
+				move()
+			}
+		case "T*", "'", "\"": // next line, and show text for ' and "
+			y -= leading * scale
+			if leading == 0 {
+				y -= scale
+			}
+			move()
+			if op != "T*" && len(args) > 0 {
+				show(args[len(args)-1].RawString())
+			}
+		case "Tj":
+			if len(args) == 1 {
+				show(args[0].RawString())
+			}
+		case "TJ":
+			if len(args) == 1 {
+				for i := 0; i < args[0].Len(); i++ {
+					v := args[0].Index(i)
+					if v.Kind() == pdf.String {
+						show(v.RawString())
+						continue
+					}
+					if v.Float64() < -wordGap {
+						b.WriteString(" ")
+					}
+					advance -= v.Float64() / 1000 * fontSize
+				}
+			}
+		}
+	})
+
+	return b.String(), nil
+}
+
+// fontInfo caches the encoding and glyph widths of a font
+type fontInfo struct {
+	enc    pdf.TextEncoding
+	first  int
+	widths []float64
+}
+
+func newFontInfo(font pdf.Font) *fontInfo {
+	return &fontInfo{
+		enc:    font.Encoder(),
+		first:  font.FirstChar(),
+		widths: font.Widths(),
+	}
+}
+
+// width returns the width of a glyph in thousandths of a text space unit
+func (f *fontInfo) width(code int) float64 {
+	if code < f.first || code-f.first >= len(f.widths) {
+		return 0
+	}
+	return f.widths[code-f.first]
+}
+
+// Format joins pages into one text, with a "[Page N]" line before each page so the
+// page numbers survive chunking and can be cited
+func Format(pages []Page) string {
+	var b strings.Builder
+	for i, page := range pages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[Page %d]\n%s", page.Number, page.Text)
+	}
+	return b.String()
+}
+
+// PageNumber returns the page number if line is a page marker written by Format
+func PageNumber(line string) (int, bool) {
+	match := pageMarker.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.Atoi(match[1])
+	return number, err == nil
+}
@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF assembles a single-page PDF showing each line with Tj, with a correct xref table
+func buildPDF(lines ...string) []byte {
+	var stream strings.Builder
+	stream.WriteString("BT /F1 12 Tf 72 720 Td\n")
+	for i, line := range lines {
+		if i > 0 {
+			stream.WriteString("0 -14 Td\n")
+		}
+		fmt.Fprintf(&stream, "(%s) Tj\n", line)
+	}
+	stream.WriteString("ET")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", stream.Len(), stream.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	pages, err := Extract(buildPDF("Rotate the database credentials", "every 90 days"))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(pages) != 1 || pages[0].Number != 1 {
+		t.Fatalf("Extract() pages = %+v, want one page numbered 1", pages)
+	}
+	if want := "Rotate the database credentials\nevery 90 days"; pages[0].Text != want {
+		t.Errorf("Extract() text = %q, want %q", pages[0].Text, want)
+	}
+}
+
+func TestExtractMalformed(t *testing.T) {
+	valid := buildPDF("hello")
+	xref := bytes.Index(valid, []byte("xref\n"))
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"empty", nil},
+		{"header only", []byte("%PDF-1.4\n")},
+		{"garbage", append([]byte("%PDF-1.7\n"), bytes.Repeat([]byte{0xff, 0x00, 'x'}, 200)...)},
+		{"truncated", valid[:len(valid)/2]},
+		{"truncated xref", valid[:xref+20]},
+		{"bad startxref", bytes.Replace(valid, []byte(fmt.Sprintf("startxref\n%d", xref)), []byte("startxref\n7"), 1)},
+		{"bad trailer", bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Root 9 0 R"), 1)},
+		// The library panics resolving an object whose number doesn't match the xref entry
+		{"mismatched object number", bytes.Replace(valid, []byte("2 0 obj"), []byte("7 0 obj"), 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := Extract(tt.content)
+			if err == nil {
+				t.Fatalf("Extract() = %+v, want an error", pages)
+			}
+		})
+	}
+}
+
+func TestFormatAndPageNumber(t *testing.T) {
+	text := Format([]Page{{Number: 2, Text: "first"}, {Number: 5, Text: "second"}})
+	if want := "[Page 2]\nfirst\n\n[Page 5]\nsecond"; text != want {
+		t.Fatalf("Format() = %q, want %q", text, want)
+	}
+
+	tests := []struct {
+		line   string
+		number int
+		ok     bool
+	}{
+		{"[Page 3]", 3, true},
+		{"  [Page 12]  ", 12, true},
+		{"[Page]", 0, false},
+		{"see [Page 3]", 0, false},
+		{"[page 3]", 0, false},
+	}
+	for _, tt := range tests {
+		number, ok := PageNumber(tt.line)
+		if number != tt.number || ok != tt.ok {
+			t.Errorf("PageNumber(%q) = %d, %v, want %d, %v", tt.line, number, ok, tt.number, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// Defaults for batched embedding during ingestion
+const (
+	defaultEmbedBatchSize   = 100
+	defaultEmbedConcurrency = 4
+)
+
+// ProgressFunc is called as chunks get embedded, with the number done so far and the total
+type ProgressFunc func(done, total int)
+
+// batch is a range of texts embedded in one request
+type batch struct {
+	start, end int
+}
+
+// embedAll embeds texts in batches that fit the configured batch size and the provider's
+// limits, with up to r.embedConcurrency requests in flight. The first failure stops the
+// remaining batches; the vectors of texts that weren't embedded are nil.
+func (r *RAG) embedAll(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	if len(texts) == 0 {
+		return vectors, nil
+	}
+	if r.embedder == nil {
+		return vectors, fmt.Errorf("no embedding provider configured")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		done     int
+	)
+	slots := make(chan struct{}, max(r.embedConcurrency, 1))
+
+	for _, b := range r.batches(texts) {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(b batch) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result, err := r.embedder.Embed(ctx, texts[b.start:b.end])
+			if err == nil && len(result) != b.end-b.start {
+				err = fmt.Errorf("expected %d embeddings, got %d", b.end-b.start, len(result))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			copy(vectors[b.start:b.end], result)
+			done += b.end - b.start
+			if r.progress != nil {
+				r.progress(done, len(texts))
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	return vectors, firstErr
+}
+
+// batches splits texts into consecutive ranges within the batch size and provider limits.
+// A text that exceeds the token limit on its own gets a batch to itself.
+func (r *RAG) batches(texts []string) []batch {
+	limits := r.embedder.Limits()
+	size := r.embedBatchSize
+	if limits.Inputs > 0 && (size <= 0 || size > limits.Inputs) {
+		size = limits.Inputs
+	}
+
+	var batches []batch
+	current := batch{}
+	tokens := 0
+	for i, text := range texts {
+		n := estimateTokens(text)
+		full := size > 0 && current.end-current.start >= size
+		if limits.Tokens > 0 && tokens+n > limits.Tokens {
+			full = true
+		}
+		if full && current.end > current.start {
+			batches = append(batches, current)
+			current = batch{start: i, end: i}
+			tokens = 0
+		}
+		current.end = i + 1
+		tokens += n
+	}
+	batches = append(batches, current)
+
+	return batches
+}
+
+// estimateTokens errs on the high side of the usual four characters per token
+func estimateTokens(text string) int {
+	return utf8.RuneCountInString(text)/3 + 1
+}
@@ -0,0 +1,140 @@
+package rag
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// BenchmarkResult compares approximate graph search with an exhaustive scan
+type BenchmarkResult struct {
+	Vectors    int
+	Dimensions int
+	Queries    int
+	K          int
+	EfSearch   int
+	BuildTime  time.Duration
+	BruteForce time.Duration // average per query
+	Graph      time.Duration // average per query
+	Recall     float64       // fraction of the exact top k that the graph search found, counting ties
+}
+
+// BenchmarkSearch builds a graph over vectors and runs every query through it and
+// through an exhaustive scan, measuring latency and the recall of the top k
+func BenchmarkSearch(vectors, queries [][]float32, k, efSearch int) BenchmarkResult {
+	result := BenchmarkResult{Vectors: len(vectors), Queries: len(queries), K: k, EfSearch: efSearch}
+	if len(vectors) > 0 {
+		result.Dimensions = len(vectors[0])
+	}
+
+	chunks := make([]Chunk, len(vectors))
+	for i, vector := range vectors {
+		chunks[i] = Chunk{ID: chunkID("bench", i, ""), Vector: vector}
+	}
+
+	start := time.Now()
+	graph := buildHNSWGraph(chunks)
+	result.BuildTime = time.Since(start)
+
+	// The similarity of the k-th exact match; ties with it count as found, since either
+	// chunk is an equally good answer
+	threshold := make([]float32, len(queries))
+	expected := make([]int, len(queries))
+	start = time.Now()
+	for i, query := range queries {
+		similarities := bruteForceSearch(chunks, query, k)
+		expected[i] = len(similarities)
+		if len(similarities) > 0 {
+			threshold[i] = similarities[len(similarities)-1]
+		}
+	}
+	bruteTime := time.Since(start)
+
+	var found, total int
+	start = time.Now()
+	hits := make([][]hnswHit, len(queries))
+	for i, query := range queries {
+		hits[i] = graph.search(query, k, efSearch)
+	}
+	graphTime := time.Since(start)
+
+	for i := range queries {
+		total += expected[i]
+		for _, hit := range hits[i] {
+			if hit.similarity >= threshold[i]-1e-5 {
+				found++
+			}
+		}
+	}
+
+	if len(queries) > 0 {
+		result.BruteForce = bruteTime / time.Duration(len(queries))
+		result.Graph = graphTime / time.Duration(len(queries))
+	}
+	if total > 0 {
+		result.Recall = float64(found) / float64(total)
+	}
+	return result
+}
+
+// bruteForceSearch returns the similarities of the k chunks closest to the query, highest first
+func bruteForceSearch(chunks []Chunk, query []float32, k int) []float32 {
+	similarities := make([]float32, len(chunks))
+	for i, chunk := range chunks {
+		similarities[i] = cosineSimilarity(query, chunk.Vector)
+	}
+	sort.Slice(similarities, func(i, j int) bool { return similarities[i] > similarities[j] })
+
+	if len(similarities) > k {
+		similarities = similarities[:k]
+	}
+	return similarities
+}
+
+// SyntheticVectors generates n unit vectors grouped around random topics, roughly like
+// the embeddings of documentation split into chunks
+func SyntheticVectors(n, dimensions int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	topics := make([][]float32, max(n/50, 1))
+	for i := range topics {
+		topics[i] = randomVector(rng, dimensions, nil, 1)
+	}
+
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = randomVector(rng, dimensions, topics[rng.Intn(len(topics))], 1)
+	}
+	return vectors
+}
+
+// PerturbedQueries derives n queries from vectors by adding noise, so each has close
+// but not identical neighbours
+func PerturbedQueries(vectors [][]float32, n int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	queries := make([][]float32, 0, n)
+	for i := 0; i < n && len(vectors) > 0; i++ {
+		base := vectors[rng.Intn(len(vectors))]
+		queries = append(queries, randomVector(rng, len(base), base, 0.5))
+	}
+	return queries
+}
+
+// randomVector returns center plus gaussian noise of the given scale, normalized
+func randomVector(rng *rand.Rand, dimensions int, center []float32, noise float64) []float32 {
+	v := make([]float32, dimensions)
+	scale := noise / math.Sqrt(float64(dimensions))
+	for i := range v {
+		v[i] = float32(rng.NormFloat64() * scale)
+		if center != nil {
+			v[i] += center[i]
+		}
+	}
+
+	if n := norm(v); n > 0 {
+		for i := range v {
+			v[i] /= n
+		}
+	}
+	return v
+}
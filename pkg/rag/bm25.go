@@ -0,0 +1,130 @@
+package rag
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters, using the usual defaults
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type posting struct {
+	chunk int // position in Index.Chunks
+	freq  int
+}
+
+// keywordIndex is an in-memory BM25 index over the chunks. It is cheap to build, so it
+// isn't persisted; it is rebuilt whenever the chunks change.
+type keywordIndex struct {
+	postings  map[string][]posting
+	lengths   []int
+	avgLength float64
+}
+
+type keywordMatch struct {
+	chunk int
+	score float64
+}
+
+func newKeywordIndex(chunks []Chunk) *keywordIndex {
+	k := &keywordIndex{
+		postings: make(map[string][]posting),
+		lengths:  make([]int, len(chunks)),
+	}
+
+	var total int
+	for i, chunk := range chunks {
+		terms := tokenize(chunk.Content)
+		k.lengths[i] = len(terms)
+		total += len(terms)
+
+		freqs := make(map[string]int)
+		for _, term := range terms {
+			freqs[term]++
+		}
+		for term, freq := range freqs {
+			k.postings[term] = append(k.postings[term], posting{chunk: i, freq: freq})
+		}
+	}
+	if len(chunks) > 0 {
+		k.avgLength = float64(total) / float64(len(chunks))
+	}
+
+	return k
+}
+
+// search scores every chunk containing at least one query term and returns the best
+// limit matches, highest score first
+func (k *keywordIndex) search(query string, limit int) []keywordMatch {
+	n := float64(len(k.lengths))
+	scores := make(map[int]float64)
+
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := k.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.freq)
+			norm := 1 - bm25B + bm25B*float64(k.lengths[p.chunk])/k.avgLength
+			scores[p.chunk] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+
+	matches := make([]keywordMatch, 0, len(scores))
+	for chunk, score := range scores {
+		matches = append(matches, keywordMatch{chunk: chunk, score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].chunk < matches[j].chunk
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
+
+// tokenize lowercases text and splits it into terms. Identifiers such as
+// "kube-proxy", "--max-pods" or "s3.amazonaws.com" are kept whole and their parts
+// are added as well, so both exact and partial matches score.
+func tokenize(text string) []string {
+	var terms []string
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.'
+	})
+	for _, word := range words {
+		word = strings.Trim(word, "-_.")
+		if word == "" {
+			continue
+		}
+		terms = append(terms, word)
+
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		})
+		if len(parts) > 1 {
+			terms = append(terms, parts...)
+		}
+	}
+
+	return terms
+}
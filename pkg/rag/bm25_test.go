@@ -0,0 +1,78 @@
+package rag
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Restart the Service", []string{"restart", "the", "service"}},
+		{"set --max-pods=110", []string{"set", "max-pods", "max", "pods", "110"}},
+		{"s3.amazonaws.com.", []string{"s3.amazonaws.com", "s3", "amazonaws", "com"}},
+		{"kube_proxy (v1.29)", []string{"kube_proxy", "kube", "proxy", "v1.29", "v1", "29"}},
+		{"-- ... __", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordSearch(t *testing.T) {
+	chunks := []Chunk{
+		{Content: "restart the kubelet after changing --max-pods"},
+		{Content: "the kubelet logs are in journald"},
+		{Content: "rotate the database credentials every 90 days"},
+		{Content: "kubelet kubelet kubelet configuration reference for the kubelet and the kube-proxy"},
+	}
+	index := newKeywordIndex(chunks)
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []int // chunk positions, best first
+	}{
+		{"rare term outranks common ones", "max-pods kubelet", 10, []int{0, 3, 1}},
+		{"term frequency and shorter chunks score higher", "kubelet", 10, []int{3, 1, 0}},
+		{"repeated query terms count once", "kubelet kubelet", 10, []int{3, 1, 0}},
+		{"identifier parts match", "proxy", 10, []int{3}},
+		{"limit", "the", 2, []int{3, 1}},
+		{"no match", "terraform", 10, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int{}
+			for _, m := range index.search(tt.query, tt.limit) {
+				got = append(got, m.chunk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeywordScore(t *testing.T) {
+	// With one term in one of two equally long chunks, the score reduces to
+	// idf * (k1 + 1) / (1 + k1) = idf
+	index := newKeywordIndex([]Chunk{{Content: "alpha beta"}, {Content: "gamma delta"}})
+	matches := index.search("alpha", 10)
+	if len(matches) != 1 || matches[0].chunk != 0 {
+		t.Fatalf("search() = %+v, want a single match on chunk 0", matches)
+	}
+	if want := math.Log(1 + (2-1+0.5)/(1+0.5)); math.Abs(matches[0].score-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", matches[0].score, want)
+	}
+
+	if got := newKeywordIndex(nil).search("alpha", 10); len(got) != 0 {
+		t.Errorf("search() on an empty index = %+v, want no matches", got)
+	}
+}
@@ -0,0 +1,239 @@
+package rag
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// bundleFormat identifies knowledge base bundles, and bundleVersion is bumped whenever
+// their layout changes incompatibly
+const (
+	bundleFormat  = "cloudigest-kb-bundle"
+	bundleVersion = 1
+)
+
+// BundleInfo is the header of a bundle. It is written before the sources and chunks so
+// that a bundle can be checked without decoding all of it.
+type BundleInfo struct {
+	Format         string
+	Version        int
+	EmbeddingModel string
+	Dimensions     int
+	Sources        int
+	Chunks         int
+	CreatedAt      time.Time
+}
+
+// Bundle is a portable copy of the indexed sources with their chunks and vectors, for
+// sharing a knowledge base without everyone embedding it again
+type Bundle struct {
+	Info    BundleInfo
+	Sources []SourceRecord
+	Chunks  []Chunk
+}
+
+// bundleBody follows the header in a bundle file
+type bundleBody struct {
+	Sources []SourceRecord
+	Chunks  []Chunk
+}
+
+// Export writes the indexed sources to a gzipped bundle at path, replacing any existing
+// file atomically. The bundled example documents are left out since every installation
+// has them.
+func (r *RAG) Export(path string) (BundleInfo, error) {
+	var body bundleBody
+	exported := make(map[string]bool)
+	for _, record := range r.Sources() {
+		if record.Origin == OriginBuiltin {
+			continue
+		}
+		exported[record.Location] = true
+		body.Sources = append(body.Sources, record)
+	}
+	for _, chunk := range r.index.Chunks {
+		if exported[chunk.Source] {
+			body.Chunks = append(body.Chunks, chunk)
+		}
+	}
+	if len(body.Sources) == 0 {
+		return BundleInfo{}, fmt.Errorf("the knowledge base has no sources to export")
+	}
+
+	info := BundleInfo{
+		Format:         bundleFormat,
+		Version:        bundleVersion,
+		EmbeddingModel: r.index.EmbeddingModel,
+		Sources:        len(body.Sources),
+		Chunks:         len(body.Chunks),
+		CreatedAt:      time.Now().UTC(),
+	}
+	if len(body.Chunks) > 0 {
+		info.Dimensions = len(body.Chunks[0].Vector)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return BundleInfo{}, fmt.Errorf("failed to write bundle: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// Bundles are meant to be shared
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return BundleInfo{}, fmt.Errorf("failed to write bundle: %v", err)
+	}
+
+	zw := gzip.NewWriter(tmp)
+	encoder := gob.NewEncoder(zw)
+	if err := encoder.Encode(info); err == nil {
+		err = encoder.Encode(body)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return BundleInfo{}, fmt.Errorf("failed to write bundle: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return BundleInfo{}, fmt.Errorf("failed to write bundle: %v", err)
+	}
+	return info, nil
+}
+
+// ReadBundle reads a bundle written by Export and checks that it is complete and
+// consistent
+func ReadBundle(path string) (*Bundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %v", err)
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a knowledge base bundle: %v", path, err)
+	}
+	defer zr.Close()
+
+	decoder := gob.NewDecoder(zr)
+	var info BundleInfo
+	if err := decoder.Decode(&info); err != nil || info.Format != bundleFormat {
+		return nil, fmt.Errorf("%s is not a knowledge base bundle", path)
+	}
+	if info.Version != bundleVersion {
+		return nil, fmt.Errorf("bundle format version %d is not supported (expected %d); export it again with this version of cloudigest",
+			info.Version, bundleVersion)
+	}
+
+	var body bundleBody
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %v", err)
+	}
+	if len(body.Sources) != info.Sources || len(body.Chunks) != info.Chunks {
+		return nil, fmt.Errorf("bundle is incomplete: expected %d sources and %d chunks, found %d and %d",
+			info.Sources, info.Chunks, len(body.Sources), len(body.Chunks))
+	}
+
+	sources := make(map[string]bool, len(body.Sources))
+	for _, record := range body.Sources {
+		sources[record.Location] = true
+	}
+	for _, chunk := range body.Chunks {
+		if !sources[chunk.Source] {
+			return nil, fmt.Errorf("bundle has chunks of %s, which is not one of its sources", chunk.Source)
+		}
+		if len(chunk.Vector) != info.Dimensions {
+			return nil, fmt.Errorf("bundle has a %d-dimensional vector, expected %d", len(chunk.Vector), info.Dimensions)
+		}
+	}
+
+	return &Bundle{Info: info, Sources: body.Sources, Chunks: body.Chunks}, nil
+}
+
+// ImportResult counts what importing a bundle did to each of its sources
+type ImportResult struct {
+	Added     int
+	Updated   int // replaced a source with the same location and different content
+	Unchanged int
+	Removed   int // sources that weren't in the bundle, when replacing the index
+	Chunks    int // chunks added or replaced
+}
+
+// Import adds the sources of a bundle to the index with their vectors, without
+// embedding anything. Vectors from different embedding models can't be compared, so a
+// bundle embedded with another model than the configured one is refused. Sources that
+// are already indexed are replaced when their content or chunking differs, and with
+// replace, sources that aren't in the bundle are removed. New sources get the import
+// origin, so that syncing the configured sources keeps them.
+func (r *RAG) Import(bundle *Bundle, replace bool) (ImportResult, error) {
+	var result ImportResult
+
+	model := r.EmbeddingModel()
+	if bundle.Info.EmbeddingModel != model {
+		return result, fmt.Errorf("bundle was embedded with %s but the knowledge base uses %s; configure rag.embeddings "+
+			"to use %s to import it, or ask for a bundle exported with %s", bundle.Info.EmbeddingModel, model,
+			bundle.Info.EmbeddingModel, model)
+	}
+	if stats := r.Stats(); !replace && stats.Dimensions > 0 && bundle.Info.Dimensions > 0 && stats.Dimensions != bundle.Info.Dimensions {
+		return result, fmt.Errorf("bundle has %d-dimensional vectors but the knowledge base has %d-dimensional ones",
+			bundle.Info.Dimensions, stats.Dimensions)
+	}
+
+	if replace {
+		inBundle := make(map[string]bool, len(bundle.Sources))
+		for _, record := range bundle.Sources {
+			inBundle[record.Location] = true
+		}
+		for location := range r.index.Sources {
+			if !inBundle[location] {
+				r.index.removeSource(location)
+				result.Removed++
+			}
+		}
+		if result.Removed > 0 {
+			r.changed()
+		}
+	}
+
+	chunks := make(map[string][]Chunk, len(bundle.Sources))
+	for _, chunk := range bundle.Chunks {
+		chunks[chunk.Source] = append(chunks[chunk.Source], chunk)
+	}
+
+	records := append([]SourceRecord(nil), bundle.Sources...)
+	sort.Slice(records, func(i, j int) bool { return records[i].Location < records[j].Location })
+	for _, record := range records {
+		origin := OriginImport
+		if existing, ok := r.index.Sources[record.Location]; ok {
+			if existing.Hash == record.Hash && existing.Chunker == record.Chunker &&
+				existing.ChunkSize == record.ChunkSize && existing.Overlap == record.Overlap {
+				result.Unchanged++
+				continue
+			}
+			// A source the local configuration or kb add put there stays theirs
+			origin = existing.Origin
+			result.Updated++
+		} else {
+			result.Added++
+		}
+
+		r.index.removeSource(record.Location)
+		r.index.addChunks(chunks[record.Location])
+		record.Origin = origin
+		r.index.Sources[record.Location] = &record
+		result.Chunks += len(chunks[record.Location])
+		r.changed()
+	}
+
+	return result, nil
+}
@@ -0,0 +1,225 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newTestRAG returns a knowledge base in a temporary directory that embeds locally
+func newTestRAG(t *testing.T, dimensions int) *RAG {
+	t.Helper()
+	r := NewRAGWithClaude("")
+	r.SetEmbedder(NewLocalEmbedder(dimensions))
+	if err := r.Open(t.TempDir()); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return r
+}
+
+func indexTestDocuments(t *testing.T, r *RAG, docs ...Document) {
+	t.Helper()
+	_, errs := r.IndexDocuments(docs)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("IndexDocuments() error = %v", err)
+		}
+	}
+}
+
+var (
+	runbookDoc = Document{Content: "# Restart\nRestart the web service with systemctl restart web.", Source: "Runbook",
+		Type: "runbook", Location: "/srv/docs/runbook.md", Origin: OriginConfig}
+	guideDoc = Document{Content: "Rotate the database credentials every 90 days.", Source: "Guide",
+		Type: "documentation", Location: "https://docs.example.com/guide", Origin: OriginManual}
+	exampleDoc = Document{Content: "Example document bundled with every installation.", Source: "Example",
+		Type: "documentation", Location: "builtin:Example", Origin: OriginBuiltin}
+)
+
+// chunksBySource groups the indexed chunks by source
+func chunksBySource(r *RAG) map[string][]Chunk {
+	chunks := make(map[string][]Chunk)
+	for _, chunk := range r.index.Chunks {
+		chunks[chunk.Source] = append(chunks[chunk.Source], chunk)
+	}
+	return chunks
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	exporter := newTestRAG(t, 64)
+	indexTestDocuments(t, exporter, runbookDoc, guideDoc, exampleDoc)
+
+	path := filepath.Join(t.TempDir(), "kb.bundle")
+	info, err := exporter.Export(path)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if info.Sources != 2 || info.EmbeddingModel != "local-hash-64" || info.Dimensions != 64 {
+		t.Errorf("Export() = %+v, want 2 sources of local-hash-64 with 64 dimensions", info)
+	}
+
+	bundle, err := ReadBundle(path)
+	if err != nil {
+		t.Fatalf("ReadBundle() error = %v", err)
+	}
+	if bundle.Info != info {
+		t.Errorf("ReadBundle() info = %+v, want %+v", bundle.Info, info)
+	}
+
+	importer := newTestRAG(t, 64)
+	result, err := importer.Import(bundle, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if want := (ImportResult{Added: 2, Chunks: info.Chunks}); result != want {
+		t.Errorf("Import() = %+v, want %+v", result, want)
+	}
+
+	// The builtin example stays behind, and everything else arrives as is
+	exported := chunksBySource(exporter)
+	delete(exported, exampleDoc.Location)
+	if got := chunksBySource(importer); !reflect.DeepEqual(got, exported) {
+		t.Errorf("imported chunks differ from the exported ones")
+	}
+	for _, record := range importer.Sources() {
+		if record.Origin != OriginImport {
+			t.Errorf("imported source %s has origin %q, want %q", record.Location, record.Origin, OriginImport)
+		}
+	}
+
+	// Imported vectors are searchable without embedding the sources again
+	results, err := importer.Search("rotate database credentials")
+	if err != nil || len(results) == 0 || results[0].Location != guideDoc.Location {
+		t.Errorf("Search() after import = %+v, %v, want %s first", results, err, guideDoc.Location)
+	}
+
+	// Importing the same bundle again changes nothing
+	if result, err := importer.Import(bundle, false); err != nil || result != (ImportResult{Unchanged: 2}) {
+		t.Errorf("second Import() = %+v, %v, want 2 unchanged", result, err)
+	}
+}
+
+func TestBundleImportExisting(t *testing.T) {
+	exporter := newTestRAG(t, 32)
+	indexTestDocuments(t, exporter, runbookDoc, guideDoc)
+	path := filepath.Join(t.TempDir(), "kb.bundle")
+	if _, err := exporter.Export(path); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	bundle, err := ReadBundle(path)
+	if err != nil {
+		t.Fatalf("ReadBundle() error = %v", err)
+	}
+
+	changed := guideDoc
+	changed.Content = "An older version of the guide."
+	local := Document{Content: "Only indexed locally.", Source: "Local", Type: "documentation",
+		Location: "/home/me/notes.md", Origin: OriginManual}
+
+	tests := []struct {
+		name    string
+		replace bool
+		want    ImportResult
+		sources []string
+	}{
+		{"merge", false, ImportResult{Added: 1, Updated: 1, Chunks: 2},
+			[]string{local.Location, runbookDoc.Location, guideDoc.Location}},
+		{"replace", true, ImportResult{Added: 1, Updated: 1, Removed: 1, Chunks: 2},
+			[]string{runbookDoc.Location, guideDoc.Location}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importer := newTestRAG(t, 32)
+			indexTestDocuments(t, importer, changed, local)
+
+			result, err := importer.Import(bundle, tt.replace)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Import() = %+v, want %+v", result, tt.want)
+			}
+
+			var sources []string
+			for location := range importer.index.Sources {
+				sources = append(sources, location)
+			}
+			sort.Strings(sources)
+			if !reflect.DeepEqual(sources, tt.sources) {
+				t.Errorf("sources after Import() = %v, want %v", sources, tt.sources)
+			}
+			// The updated source was added by hand, and stays that way
+			if record, _ := importer.Source(guideDoc.Location); record.Origin != OriginManual || record.Hash != contentHash(guideDoc.Content) {
+				t.Errorf("updated source = %+v, want the bundled content with origin %q", record, OriginManual)
+			}
+		})
+	}
+}
+
+func TestBundleImportModelMismatch(t *testing.T) {
+	exporter := newTestRAG(t, 64)
+	indexTestDocuments(t, exporter, guideDoc)
+	path := filepath.Join(t.TempDir(), "kb.bundle")
+	if _, err := exporter.Export(path); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	bundle, err := ReadBundle(path)
+	if err != nil {
+		t.Fatalf("ReadBundle() error = %v", err)
+	}
+
+	importer := newTestRAG(t, 32)
+	indexTestDocuments(t, importer, runbookDoc)
+	if _, err := importer.Import(bundle, false); err == nil || !strings.Contains(err.Error(), "local-hash-64") {
+		t.Errorf("Import() error = %v, want a model mismatch", err)
+	}
+	if sources := importer.Sources(); len(sources) != 1 {
+		t.Errorf("Import() with a model mismatch changed the sources to %+v", sources)
+	}
+}
+
+func TestBundleErrors(t *testing.T) {
+	if _, err := newTestRAG(t, 32).Export(filepath.Join(t.TempDir(), "empty.bundle")); err == nil {
+		t.Error("Export() of an empty knowledge base succeeded, want an error")
+	}
+
+	r := newTestRAG(t, 32)
+	indexTestDocuments(t, r, runbookDoc, guideDoc)
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "kb.bundle")
+	if _, err := r.Export(valid); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	content, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr string
+	}{
+		{"not gzip", []byte("index.gob"), "not a knowledge base bundle"},
+		{"gzip of something else", gzipped(t, "hello"), "not a knowledge base bundle"},
+		{"truncated", content[:len(content)*2/3], "failed to read bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "test.bundle")
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadBundle(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadBundle() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ReadBundle(filepath.Join(dir, "missing.bundle")); err == nil {
+		t.Error("ReadBundle() of a missing file succeeded, want an error")
+	}
+}
@@ -0,0 +1,126 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxHistoryTurns is how many previous exchanges are sent to the model with each question
+const maxHistoryTurns = 6
+
+// maxBackgroundChars bounds the background material, such as scan results, in each prompt
+const maxBackgroundChars = 24000
+
+// rewritePrompt asks the model to turn a follow-up question into a standalone search query
+const rewritePrompt = "You rewrite follow-up questions into standalone search queries for a knowledge base about cloud infrastructure. " +
+	"Given the conversation so far and a follow-up question, reply with a single query that includes every name, resource and " +
+	"topic from the conversation that the question refers to. Reply with the query only, without quotes or explanation. " +
+	"If the question already stands on its own, repeat it unchanged."
+
+// Turn is one exchange of a conversation
+type Turn struct {
+	Question   string
+	Query      string // the standalone query used for retrieval
+	Answer     string
+	References []SearchResult
+}
+
+// Conversation is a multi-turn chat over the knowledge base. Each question is retrieved
+// for on its own, after follow-ups are rewritten into standalone queries, and answered
+// with the recent history in view.
+type Conversation struct {
+	rag        *RAG
+	background string
+	Turns      []Turn
+}
+
+// NewConversation starts a conversation. Background, such as the latest scan results,
+// is given to the model with every question; it may be empty.
+func (r *RAG) NewConversation(background string) *Conversation {
+	if len(background) > maxBackgroundChars {
+		// Cut on a character boundary
+		end := maxBackgroundChars
+		for end > 0 && !utf8.RuneStart(background[end]) {
+			end--
+		}
+		background = background[:end] + "\n[truncated]"
+	}
+	return &Conversation{rag: r, background: background}
+}
+
+// Ask answers the next question of the conversation
+func (c *Conversation) Ask(question string) (*Turn, error) {
+	query := question
+	if len(c.Turns) > 0 {
+		rewritten, err := c.rewrite(question)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite follow-up question: %v", err)
+		}
+		query = rewritten
+	}
+
+	results, err := c.rag.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	system := systemPrompt
+	if c.background != "" {
+		system += "\n\nBackground on the user's infrastructure, from their latest scans. Refer to it where it is relevant, " +
+			"but only cite the numbered knowledge base sources:\n\n" + c.background
+	}
+
+	messages := c.history()
+	messages = append(messages, message{content: c.rag.buildContext(results) + "\n\nQuestion: " + question})
+	answer, err := c.rag.complete(system, messages, c.rag.maxTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Turns = append(c.Turns, Turn{Question: question, Query: query, Answer: answer, References: results})
+	return &c.Turns[len(c.Turns)-1], nil
+}
+
+// Reset forgets the conversation history but keeps the background
+func (c *Conversation) Reset() {
+	c.Turns = nil
+}
+
+// history returns the most recent exchanges as alternating questions and answers
+func (c *Conversation) history() []message {
+	turns := c.Turns
+	if len(turns) > maxHistoryTurns {
+		turns = turns[len(turns)-maxHistoryTurns:]
+	}
+
+	var messages []message
+	for _, turn := range turns {
+		messages = append(messages, message{content: turn.Question}, message{assistant: true, content: turn.Answer})
+	}
+	return messages
+}
+
+// rewrite turns a follow-up question into a query that can be retrieved for on its own
+func (c *Conversation) rewrite(question string) (string, error) {
+	var transcript strings.Builder
+	for _, m := range c.history() {
+		role := "User"
+		if m.assistant {
+			role = "Assistant"
+		}
+		transcript.WriteString(role + ": " + m.content + "\n\n")
+	}
+
+	prompt := "Conversation:\n\n" + transcript.String() + "Follow-up question: " + question
+	query, err := c.rag.complete(rewritePrompt, []message{{content: prompt}}, 200)
+	if err != nil {
+		return "", err
+	}
+
+	query = strings.Trim(strings.TrimSpace(query), `"`)
+	if query == "" {
+		return question, nil
+	}
+	return query, nil
+}
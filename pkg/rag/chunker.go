@@ -0,0 +1,475 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cloudigest/pkg/pdf"
+)
+
+// Chunker names, as used in the rag.chunkers configuration
+const (
+	ChunkerToken    = "token"
+	ChunkerMarkdown = "markdown"
+	ChunkerYAML     = "yaml"
+)
+
+// TextChunk is a piece of a document with the heading path it falls under and, for
+// PDFs, the pages it spans
+type TextChunk struct {
+	Content string
+	Section string // e.g. "Install > Prerequisites", or "Deployment/web" for YAML
+	Pages   string // e.g. "3" or "3-4"
+}
+
+// Chunker splits a document into pieces that are embedded separately
+type Chunker interface {
+	Split(text string) []TextChunk
+}
+
+// NewChunker returns the named chunker. Sizes are in tokens, approximated by
+// whitespace-separated words; overlap is how many tokens consecutive chunks share.
+func NewChunker(name string, size, overlap int) (Chunker, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+	if overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("chunk overlap must be between 0 and the chunk size")
+	}
+
+	switch name {
+	case ChunkerToken:
+		return &TokenChunker{Size: size, Overlap: overlap}, nil
+	case ChunkerMarkdown:
+		return &MarkdownChunker{Size: size, Overlap: overlap}, nil
+	case ChunkerYAML:
+		return &YAMLChunker{Size: size}, nil
+	default:
+		return nil, fmt.Errorf("unknown chunker %q (expected %s, %s or %s)", name, ChunkerToken, ChunkerMarkdown, ChunkerYAML)
+	}
+}
+
+// TokenChunker splits text into fixed-size windows of tokens, each starting Overlap
+// tokens before the previous one ended. It ignores structure, so it suits prose and
+// extracted PDF text. Markdown headings and PDF page markers are still tracked for
+// the chunk metadata.
+type TokenChunker struct {
+	Size    int
+	Overlap int
+}
+
+func (c *TokenChunker) Split(text string) []TextChunk {
+	var words []string
+	var sections []string
+	var pages []int
+
+	var headings headingPath
+	page := 0
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if number, ok := pdf.PageNumber(trimmed); ok {
+			// Markers only carry the page number; their words would pollute the chunks
+			page = number
+			continue
+		} else if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		} else if level, heading := markdownHeading(trimmed); !inFence && level > 0 {
+			headings.enter(level, heading)
+		}
+
+		section := headings.String()
+		for _, word := range strings.Fields(line) {
+			words = append(words, word)
+			sections = append(sections, section)
+			pages = append(pages, page)
+		}
+	}
+
+	var chunks []TextChunk
+	for start := 0; start < len(words); start += c.Size - c.Overlap {
+		end := min(start+c.Size, len(words))
+		chunks = append(chunks, TextChunk{
+			Content: strings.Join(words[start:end], " "),
+			Section: sections[start],
+			Pages:   pageRange(pages[start], pages[end-1]),
+		})
+		if end == len(words) {
+			break
+		}
+	}
+
+	return chunks
+}
+
+// MarkdownChunker splits Markdown into paragraphs, lists and fenced code blocks and
+// packs them into chunks of up to Size tokens. A heading starts a new chunk unless
+// the current one is still small, and code blocks are never split unless a single
+// block is larger than Size, in which case each part is fenced again. When a chunk is
+// full, trailing blocks of up to Overlap tokens are repeated at the start of the next.
+type MarkdownChunker struct {
+	Size    int
+	Overlap int
+}
+
+type block struct {
+	text    string
+	words   int
+	section string
+	heading bool
+	code    bool
+}
+
+func (c *MarkdownChunker) Split(text string) []TextChunk {
+	p := &packer{size: c.Size, overlap: c.Overlap, separator: "\n\n"}
+
+	var headings headingPath
+	var paragraph []string
+	var fence []string
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			p.add(newBlock(strings.Join(paragraph, "\n"), headings.String()))
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != nil {
+			fence = append(fence, line)
+			if strings.HasPrefix(trimmed, "```") {
+				b := newBlock(strings.Join(fence, "\n"), headings.String())
+				b.code = true
+				p.add(b)
+				fence = nil
+			}
+			continue
+		}
+
+		switch level, heading := markdownHeading(trimmed); {
+		case strings.HasPrefix(trimmed, "```"):
+			flushParagraph()
+			fence = []string{line}
+		case level > 0:
+			flushParagraph()
+			headings.enter(level, heading)
+			b := newBlock(line, headings.String())
+			b.heading = true
+			p.add(b)
+		case trimmed == "":
+			flushParagraph()
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	// An unterminated fence runs to the end of the document
+	if fence != nil {
+		b := newBlock(strings.Join(fence, "\n"), headings.String())
+		b.code = true
+		p.add(b)
+	}
+	flushParagraph()
+
+	return p.finish()
+}
+
+// YAMLChunker keeps each YAML document (separated by "---") in a chunk of its own,
+// labelled with its kind and name when it is a Kubernetes-style manifest. Documents
+// larger than Size are split between top-level keys.
+type YAMLChunker struct {
+	Size int
+}
+
+var (
+	yamlKind = regexp.MustCompile(`^kind:\s*["']?([^"'\s#]+)`)
+	yamlName = regexp.MustCompile(`^\s+name:\s*["']?([^"'\s#]+)`)
+)
+
+func (c *YAMLChunker) Split(text string) []TextChunk {
+	var chunks []TextChunk
+	for _, doc := range splitYAMLDocuments(text) {
+		label := yamlLabel(doc)
+		p := &packer{size: c.Size, separator: "\n"}
+
+		// Each top-level key and everything indented below it is one block
+		var lines []string
+		for _, line := range strings.Split(doc, "\n") {
+			topLevel := line != "" && line[0] != ' ' && line[0] != '\t' && line[0] != '-' && line[0] != '#'
+			if topLevel && len(lines) > 0 {
+				p.add(newBlock(strings.Join(lines, "\n"), label))
+				lines = nil
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) > 0 {
+			p.add(newBlock(strings.Join(lines, "\n"), label))
+		}
+
+		chunks = append(chunks, p.finish()...)
+	}
+	return chunks
+}
+
+func splitYAMLDocuments(text string) []string {
+	var docs []string
+	var current []string
+	flush := func() {
+		if doc := strings.TrimSpace(strings.Join(current, "\n")); doc != "" {
+			docs = append(docs, strings.Join(current, "\n"))
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if line == "---" || strings.HasPrefix(line, "--- ") {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return docs
+}
+
+// yamlLabel returns "Kind/name" for a manifest, using the name directly under metadata
+func yamlLabel(doc string) string {
+	var kind, name string
+	inMetadata := false
+	for _, line := range strings.Split(doc, "\n") {
+		if match := yamlKind.FindStringSubmatch(line); match != nil {
+			kind = match[1]
+		}
+		if strings.HasPrefix(line, "metadata:") {
+			inMetadata = true
+			continue
+		}
+		if inMetadata {
+			if line != "" && line[0] != ' ' {
+				inMetadata = false
+			} else if match := yamlName.FindStringSubmatch(line); match != nil && name == "" {
+				name = match[1]
+			}
+		}
+	}
+
+	switch {
+	case kind != "" && name != "":
+		return kind + "/" + name
+	case kind != "":
+		return kind
+	default:
+		return name
+	}
+}
+
+// packer accumulates blocks into chunks of up to size tokens
+type packer struct {
+	size      int
+	overlap   int
+	separator string // placed between blocks
+	chunks    []TextChunk
+	current   []block
+	words     int
+	carried   int // blocks at the start of current repeated from the previous chunk
+}
+
+func newBlock(text, section string) block {
+	return block{text: text, words: len(strings.Fields(text)), section: section}
+}
+
+func (p *packer) add(b block) {
+	if b.words == 0 {
+		return
+	}
+
+	// Start sections in a new chunk, unless the current chunk is still small
+	if b.heading && p.words >= p.size/4 {
+		p.flush(false)
+	}
+
+	if p.words+b.words > p.size {
+		p.flush(true)
+	}
+
+	if b.words > p.size {
+		for _, part := range splitBlock(b, p.size) {
+			p.current = append(p.current, part)
+			p.words += part.words
+			p.flush(false)
+		}
+		return
+	}
+
+	p.current = append(p.current, b)
+	p.words += b.words
+}
+
+// flush emits the current chunk, optionally repeating its trailing blocks at the start
+// of the next chunk. Trailing headings are held back to introduce the next chunk.
+func (p *packer) flush(overlap bool) {
+	end := len(p.current)
+	for end > p.carried && p.current[end-1].heading {
+		end--
+	}
+	pending := append([]block(nil), p.current[end:]...)
+
+	// Overlap and headings alone don't make a chunk
+	if end == p.carried {
+		p.reset(nil, pending)
+		return
+	}
+
+	emitted := p.current[:end]
+	var parts []string
+	for _, b := range emitted {
+		parts = append(parts, b.text)
+	}
+	p.chunks = append(p.chunks, TextChunk{
+		Content: strings.Join(parts, p.separator),
+		Section: emitted[0].section,
+	})
+
+	var carried []block
+	if overlap && p.overlap > 0 && len(pending) == 0 {
+		section := emitted[len(emitted)-1].section
+		carriedWords := 0
+		for i := len(emitted) - 1; i > 0; i-- {
+			b := emitted[i]
+			if b.heading || b.section != section || carriedWords+b.words > p.overlap {
+				break
+			}
+			carried = append([]block{b}, carried...)
+			carriedWords += b.words
+		}
+	}
+	p.reset(carried, pending)
+}
+
+// reset starts the next chunk with blocks carried over as overlap and pending headings
+func (p *packer) reset(carried, pending []block) {
+	p.current = append(carried, pending...)
+	p.carried = len(carried)
+	p.words = 0
+	for _, b := range p.current {
+		p.words += b.words
+	}
+}
+
+func (p *packer) finish() []TextChunk {
+	p.flush(false)
+	// Headings at the very end of a document have nothing to introduce
+	if len(p.current) > p.carried {
+		p.carried = 0
+		for i := range p.current {
+			p.current[i].heading = false
+		}
+		p.flush(false)
+	}
+	return p.chunks
+}
+
+// splitBlock splits a block larger than size into parts of at most size tokens, on
+// line boundaries where possible. Code blocks are fenced again in every part.
+func splitBlock(b block, size int) []block {
+	lines := strings.Split(b.text, "\n")
+	var opening string
+	if b.code && len(lines) >= 2 {
+		opening = lines[0]
+		lines = lines[1:]
+		if strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```") {
+			lines = lines[:len(lines)-1]
+		}
+	}
+
+	var parts []block
+	var current []string
+	words := 0
+	emit := func() {
+		if len(current) == 0 {
+			return
+		}
+		text := strings.Join(current, "\n")
+		if b.code {
+			text = opening + "\n" + text + "\n```"
+		}
+		parts = append(parts, block{text: text, words: words, section: b.section, code: b.code})
+		current = nil
+		words = 0
+	}
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		// A single line longer than a chunk is split between words
+		for len(fields) > size {
+			emit()
+			current = []string{strings.Join(fields[:size], " ")}
+			words = size
+			emit()
+			fields = fields[size:]
+			line = strings.Join(fields, " ")
+		}
+		if words+len(fields) > size {
+			emit()
+		}
+		current = append(current, line)
+		words += len(fields)
+	}
+	emit()
+
+	return parts
+}
+
+// headingPath tracks the Markdown headings enclosing the current line
+type headingPath []string
+
+func (h *headingPath) enter(level int, heading string) {
+	path := *h
+	if level <= len(path) {
+		path = path[:level-1]
+	}
+	for len(path) < level-1 {
+		path = append(path, "")
+	}
+	*h = append(path, heading)
+}
+
+func (h headingPath) String() string {
+	var parts []string
+	for _, heading := range h {
+		if heading != "" {
+			parts = append(parts, heading)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// markdownHeading returns the level and text of an ATX heading line, or zero
+func markdownHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	heading := strings.TrimSpace(line[level:])
+	// Drop an optional closing sequence, as in "## Install ##"
+	if i := strings.LastIndex(heading, " #"); i >= 0 && strings.Trim(heading[i:], " #") == "" {
+		heading = strings.TrimSpace(heading[:i])
+	}
+	return level, heading
+}
+
+func pageRange(first, last int) string {
+	if first == 0 {
+		return ""
+	}
+	if last == first {
+		return strconv.Itoa(first)
+	}
+	return strconv.Itoa(first) + "-" + strconv.Itoa(last)
+}
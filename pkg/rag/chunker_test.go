@@ -0,0 +1,253 @@
+package rag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewChunker(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		overlap int
+		wantErr bool
+	}{
+		{ChunkerToken, 100, 10, false},
+		{ChunkerMarkdown, 100, 0, false},
+		{ChunkerYAML, 100, 0, false},
+		{"sentence", 100, 0, true},
+		{ChunkerToken, 0, 0, true},
+		{ChunkerToken, 100, 100, true},
+		{ChunkerToken, 100, -1, true},
+	}
+	for _, tt := range tests {
+		_, err := NewChunker(tt.name, tt.size, tt.overlap)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewChunker(%q, %d, %d) error = %v, want error %v", tt.name, tt.size, tt.overlap, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTokenChunker(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		overlap int
+		text    string
+		want    []TextChunk
+	}{
+		{
+			name: "windows with overlap",
+			size: 4, overlap: 1,
+			text: "one two three four five six seven",
+			want: []TextChunk{
+				{Content: "one two three four"},
+				{Content: "four five six seven"},
+			},
+		},
+		{
+			name: "short text",
+			size: 10,
+			text: "just a few words",
+			want: []TextChunk{{Content: "just a few words"}},
+		},
+		{
+			name: "empty",
+			size: 10,
+			text: "  \n\n ",
+		},
+		{
+			name: "headings become sections",
+			size: 5,
+			text: "# Install\nget the binary\n## Linux\nuse the tarball here\n```\n# not a heading\n```",
+			want: []TextChunk{
+				{Content: "# Install get the binary", Section: "Install"},
+				{Content: "## Linux use the tarball", Section: "Install > Linux"},
+				{Content: "here ``` # not a", Section: "Install > Linux"},
+				{Content: "heading ```", Section: "Install > Linux"},
+			},
+		},
+		{
+			name: "page markers set pages and are dropped",
+			size: 4,
+			text: "[Page 1]\nalpha beta gamma\n\n[Page 2]\ndelta epsilon\n\n[Page 5]\nzeta eta theta iota",
+			want: []TextChunk{
+				{Content: "alpha beta gamma delta", Pages: "1-2"},
+				{Content: "epsilon zeta eta theta", Pages: "2-5"},
+				{Content: "iota", Pages: "5"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&TokenChunker{Size: tt.size, Overlap: tt.overlap}).Split(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownChunker(t *testing.T) {
+	code := "```sh\n" + strings.Repeat("echo step\n", 6) + "```"
+
+	tests := []struct {
+		name    string
+		size    int
+		overlap int
+		text    string
+		want    []TextChunk
+	}{
+		{
+			name: "paragraphs packed together",
+			size: 9,
+			text: "first paragraph here\n\nsecond one\n\nthird paragraph that overflows it",
+			want: []TextChunk{
+				{Content: "first paragraph here\n\nsecond one"},
+				{Content: "third paragraph that overflows it"},
+			},
+		},
+		{
+			name: "headings start chunks",
+			size: 12,
+			text: "# Backups\nnightly snapshots are kept for a week\n\n## Restore\nrestore from the latest snapshot",
+			want: []TextChunk{
+				{Content: "# Backups\n\nnightly snapshots are kept for a week", Section: "Backups"},
+				{Content: "## Restore\n\nrestore from the latest snapshot", Section: "Backups > Restore"},
+			},
+		},
+		{
+			name: "code blocks kept whole",
+			size: 14,
+			text: "run this:\n\n" + code + "\n\ndone",
+			want: []TextChunk{
+				{Content: "run this:"},
+				{Content: code},
+				{Content: "done"},
+			},
+		},
+		{
+			name: "large code blocks fenced again",
+			size: 6,
+			text: code,
+			want: []TextChunk{
+				{Content: "```sh\necho step\necho step\necho step\n```"},
+				{Content: "```sh\necho step\necho step\necho step\n```"},
+			},
+		},
+		{
+			name: "overlap repeats trailing blocks",
+			size: 6, overlap: 2,
+			text: "aa bb cc\n\ndd ee\n\nff gg hh",
+			want: []TextChunk{
+				{Content: "aa bb cc\n\ndd ee"},
+				{Content: "dd ee\n\nff gg hh"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&MarkdownChunker{Size: tt.size, Overlap: tt.overlap}).Split(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLChunker(t *testing.T) {
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    name: ignored
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-svc
+`
+	tests := []struct {
+		name string
+		size int
+		text string
+		want []TextChunk
+	}{
+		{
+			name: "one chunk per document",
+			size: 50,
+			text: manifests,
+			want: []TextChunk{
+				{Content: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  labels:\n    name: ignored\nspec:\n  replicas: 2", Section: "Deployment/web"},
+				{Content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web-svc\n", Section: "Service/web-svc"},
+			},
+		},
+		{
+			name: "large documents split between top-level keys",
+			size: 7,
+			text: "kind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  a: one\n  b: two\n  c: three",
+			want: []TextChunk{
+				{Content: "kind: ConfigMap\nmetadata:\n  name: settings", Section: "ConfigMap/settings"},
+				{Content: "data:\n  a: one\n  b: two\n  c: three", Section: "ConfigMap/settings"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&YAMLChunker{Size: tt.size}).Split(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkerFor(t *testing.T) {
+	r := &RAG{chunkers: map[string]string{"runbook": ChunkerMarkdown, "manual": ChunkerYAML}}
+	tests := []struct {
+		docType string
+		format  string
+		want    string
+	}{
+		{"runbook", "text", ChunkerMarkdown},
+		{"manual", "markdown", ChunkerYAML},
+		{"runbook", "pdf", ChunkerToken},
+		{"", "pdf", ChunkerToken},
+		{"", "html", ChunkerMarkdown},
+		{"", "markdown", ChunkerMarkdown},
+		{"", "yaml", ChunkerYAML},
+		{"", "json", ChunkerToken},
+		{"", "text", ChunkerToken},
+	}
+	for _, tt := range tests {
+		if got := r.chunkerFor(tt.docType, tt.format); got != tt.want {
+			t.Errorf("chunkerFor(%q, %q) = %q, want %q", tt.docType, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownHeading(t *testing.T) {
+	tests := []struct {
+		line    string
+		level   int
+		heading string
+	}{
+		{"# Title", 1, "Title"},
+		{"### Deep ###", 3, "Deep"},
+		{"## C# tips", 2, "C# tips"},
+		{"#NoSpace", 0, ""},
+		{"#######  Seven", 0, ""},
+		{"#", 0, ""},
+		{"plain", 0, ""},
+	}
+	for _, tt := range tests {
+		level, heading := markdownHeading(tt.line)
+		if level != tt.level || heading != tt.heading {
+			t.Errorf("markdownHeading(%q) = %d, %q, want %d, %q", tt.line, level, heading, tt.level, tt.heading)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package rag
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Defaults for crawling a documentation site
+const (
+	DefaultCrawlDepth = 3
+	DefaultCrawlPages = 100
+)
+
+// maxSitemaps bounds the sitemaps read through sitemap indexes
+const maxSitemaps = 20
+
+// defaultMaxSitemapSize is the largest uncompressed sitemap the sitemap protocol allows
+const defaultMaxSitemapSize = 50 << 20
+
+// maxCrawlDelay caps the Crawl-delay honoured from robots.txt
+const maxCrawlDelay = 10 * time.Second
+
+// CrawlerAgent is the user agent the crawler identifies as in robots.txt
+const CrawlerAgent = "cloudigest"
+
+// FetchFunc fetches a URL and returns its content and Content-Type. It must fail for
+// error statuses.
+type FetchFunc func(location string) ([]byte, string, error)
+
+// CrawlOptions configure a crawl of a documentation site
+type CrawlOptions struct {
+	Start    string
+	Sitemap  string   // sitemap URL; by default the sitemaps listed in robots.txt, or /sitemap.xml
+	MaxDepth int      // link hops followed from the start page and the sitemap pages
+	MaxPages int      // pages fetched at most
+	Include  []string // glob patterns for URL paths, e.g. docs/concepts/**
+	Exclude  []string
+	MaxSize  int64 // limit on decompressed sitemaps; fetched pages are limited by the FetchFunc
+	Progress func(pages int)
+}
+
+// CrawledPage is a page found by a crawl
+type CrawledPage struct {
+	URL         string
+	Depth       int
+	Content     []byte
+	ContentType string
+}
+
+// CrawlResult is the outcome of a crawl. Pages that failed to fetch are reported rather
+// than stopping the crawl.
+type CrawlResult struct {
+	Pages      []CrawledPage
+	Failed     map[string]error
+	Disallowed int // URLs skipped because robots.txt disallows them
+	Truncated  bool
+}
+
+// Crawl fetches the start page and the pages reachable from it, on the same host and
+// under the directory of the start URL, that match the include and exclude patterns and
+// that robots.txt allows. Pages listed in the site's sitemap are crawled too. Links are
+// followed breadth first up to MaxDepth hops, and at most MaxPages pages are fetched.
+func Crawl(opts CrawlOptions, fetch FetchFunc) (*CrawlResult, error) {
+	start, err := url.Parse(opts.Start)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, fmt.Errorf("invalid crawl URL %q", opts.Start)
+	}
+	start.Fragment = ""
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultCrawlDepth
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultCrawlPages
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxSitemapSize
+	}
+	// Links to the front page resolve to "/", so the start URL must be recorded the same way
+	if start.Path == "" {
+		start.Path = "/"
+	}
+
+	c := &crawler{
+		opts:   opts,
+		fetch:  fetch,
+		host:   strings.ToLower(start.Host),
+		scope:  start.Path[:strings.LastIndex(start.Path, "/")+1],
+		seen:   make(map[string]bool),
+		result: &CrawlResult{Failed: make(map[string]error)},
+	}
+	if c.scope == "" {
+		c.scope = "/"
+	}
+
+	root := &url.URL{Scheme: start.Scheme, Host: start.Host}
+	if content, _, err := fetch(root.JoinPath("robots.txt").String()); err == nil {
+		c.robots = parseRobots(content, CrawlerAgent)
+	} else {
+		c.robots = &robotsRules{}
+	}
+
+	if !c.robots.allowed(start.RequestURI()) {
+		return nil, fmt.Errorf("robots.txt disallows crawling %s", opts.Start)
+	}
+
+	queue := []crawlItem{{start.String(), 0}}
+	c.seen[start.String()] = true
+	for _, location := range c.sitemapURLs(root) {
+		if u, ok := c.accept(location, start); ok {
+			queue = append(queue, crawlItem{u, 0})
+		}
+	}
+
+	for len(queue) > 0 {
+		if len(c.result.Pages)+len(c.result.Failed) == opts.MaxPages {
+			c.result.Truncated = true
+			break
+		}
+		item := queue[0]
+		queue = queue[1:]
+
+		if len(c.result.Pages)+len(c.result.Failed) > 0 && c.robots.delay > 0 {
+			time.Sleep(min(c.robots.delay, maxCrawlDelay))
+		}
+		content, contentType, err := fetch(item.url)
+		if err != nil {
+			c.result.Failed[item.url] = err
+			continue
+		}
+		c.result.Pages = append(c.result.Pages, CrawledPage{URL: item.url, Depth: item.depth, Content: content, ContentType: contentType})
+		if opts.Progress != nil {
+			opts.Progress(len(c.result.Pages))
+		}
+
+		if item.depth >= opts.MaxDepth || !isHTML(content, contentType, item.url) {
+			continue
+		}
+		base, _ := url.Parse(item.url)
+		for _, link := range htmlLinks(content) {
+			if u, ok := c.accept(link, base); ok {
+				queue = append(queue, crawlItem{u, item.depth + 1})
+			}
+		}
+	}
+
+	return c.result, nil
+}
+
+type crawlItem struct {
+	url   string
+	depth int
+}
+
+type crawler struct {
+	opts   CrawlOptions
+	fetch  FetchFunc
+	host   string
+	scope  string // path prefix pages must be under
+	robots *robotsRules
+	seen   map[string]bool
+	result *CrawlResult
+}
+
+// InCrawlScope reports whether a URL is on the same host and under the directory of a
+// crawl's start URL, which is where every page of the crawl comes from
+func InCrawlScope(start, location string) bool {
+	s, err := url.Parse(start)
+	if err != nil {
+		return false
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	scope := s.Path[:strings.LastIndex(s.Path, "/")+1]
+	return strings.EqualFold(u.Host, s.Host) && strings.HasPrefix(u.Path, scope)
+}
+
+// accept resolves a link against the page it appears on and reports whether it should
+// be crawled, returning it without its fragment. Each URL is accepted once.
+func (c *crawler) accept(link string, base *url.URL) (string, bool) {
+	u, err := base.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || strings.ToLower(u.Host) != c.host {
+		return "", false
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	location := u.String()
+	if c.seen[location] {
+		return "", false
+	}
+	c.seen[location] = true
+
+	if !strings.HasPrefix(u.Path, c.scope) {
+		return "", false
+	}
+	rel := strings.TrimPrefix(u.Path, "/")
+	if len(c.opts.Include) > 0 && !matchURLPatterns(c.opts.Include, rel) {
+		return "", false
+	}
+	if matchURLPatterns(c.opts.Exclude, rel) {
+		return "", false
+	}
+	if !c.robots.allowed(u.RequestURI()) {
+		c.result.Disallowed++
+		return "", false
+	}
+	return location, true
+}
+
+func matchURLPatterns(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(strings.TrimPrefix(pattern, "/"), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// sitemapURLs returns the page URLs listed in the configured sitemap, or in the sitemaps
+// that robots.txt lists, or in /sitemap.xml. Sitemap indexes are followed.
+func (c *crawler) sitemapURLs(root *url.URL) []string {
+	sitemaps := c.robots.sitemaps
+	if c.opts.Sitemap != "" {
+		sitemaps = []string{c.opts.Sitemap}
+	}
+	if len(sitemaps) == 0 {
+		sitemaps = []string{root.JoinPath("sitemap.xml").String()}
+	}
+
+	var pages []string
+	read := make(map[string]bool)
+	for len(sitemaps) > 0 && len(read) < maxSitemaps {
+		location := sitemaps[0]
+		sitemaps = sitemaps[1:]
+		if read[location] {
+			continue
+		}
+		read[location] = true
+
+		content, _, err := c.fetch(location)
+		if err != nil {
+			// An explicitly configured sitemap must exist
+			if location == c.opts.Sitemap {
+				c.result.Failed[location] = err
+			}
+			continue
+		}
+		urls, nested := parseSitemap(content, c.opts.MaxSize)
+		pages = append(pages, urls...)
+		sitemaps = append(sitemaps, nested...)
+	}
+	return pages
+}
+
+// parseSitemap returns the page URLs of a sitemap, or the sitemap URLs of a sitemap
+// index. Gzipped sitemaps are decompressed, and ignored if they exceed maxSize.
+func parseSitemap(content []byte, maxSize int64) ([]string, []string) {
+	if len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, nil
+		}
+		unzipped, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+		if err != nil || int64(len(unzipped)) > maxSize {
+			return nil, nil
+		}
+		content = unzipped
+	}
+
+	var doc struct {
+		XMLName xml.Name
+		URLs    []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return nil, nil
+	}
+
+	var urls, sitemaps []string
+	for _, u := range doc.URLs {
+		urls = append(urls, strings.TrimSpace(u.Loc))
+	}
+	for _, s := range doc.Sitemaps {
+		sitemaps = append(sitemaps, strings.TrimSpace(s.Loc))
+	}
+	return urls, sitemaps
+}
+
+// htmlLinks returns the href of every link on a page, resolved against its <base> if any
+func htmlLinks(content []byte) []string {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	var base *url.URL
+	if b := findElement(doc, func(n *html.Node) bool { return n.DataAtom == atom.Base }); b != nil {
+		base, _ = url.Parse(attr(b, "href"))
+	}
+
+	var links []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A && attr(n, "rel") != "nofollow" {
+			if href, ok := attrValue(n, "href"); ok {
+				if base != nil {
+					if u, err := base.Parse(href); err == nil {
+						href = u.String()
+					}
+				}
+				links = append(links, href)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return links
+}
+
+// robotsRules are the robots.txt rules that apply to the crawler
+type robotsRules struct {
+	allow    []string
+	disallow []string
+	delay    time.Duration
+	sitemaps []string
+}
+
+// parseRobots reads the rules of the group for agent, or of the * group when there is
+// none, and the sitemaps listed anywhere in the file
+func parseRobots(content []byte, agent string) *robotsRules {
+	type group struct {
+		agents          []string
+		allow, disallow []string
+		delay           time.Duration
+	}
+
+	var groups []*group
+	var current *group
+	rules := &robotsRules{}
+	inAgents := false
+	for _, line := range strings.Split(string(content), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "sitemap":
+			rules.sitemaps = append(rules.sitemaps, value)
+		case "allow", "disallow", "crawl-delay":
+			if current == nil {
+				break
+			}
+			switch key {
+			case "allow":
+				current.allow = append(current.allow, value)
+			case "disallow":
+				if value != "" {
+					current.disallow = append(current.disallow, value)
+				}
+			case "crawl-delay":
+				var seconds float64
+				if _, err := fmt.Sscan(value, &seconds); err == nil && seconds > 0 {
+					current.delay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+		inAgents = false
+	}
+
+	var matched, wildcard *group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" && wildcard == nil {
+				wildcard = g
+			} else if a != "*" && strings.Contains(strings.ToLower(agent), a) && matched == nil {
+				matched = g
+			}
+		}
+	}
+	if matched == nil {
+		matched = wildcard
+	}
+	if matched != nil {
+		rules.allow, rules.disallow, rules.delay = matched.allow, matched.disallow, matched.delay
+	}
+	return rules
+}
+
+// allowed applies the longest matching rule to a path, with Allow winning ties
+func (r *robotsRules) allowed(requestURI string) bool {
+	longestAllow, longestDisallow := -1, -1
+	for _, rule := range r.allow {
+		if robotsMatch(rule, requestURI) && len(rule) > longestAllow {
+			longestAllow = len(rule)
+		}
+	}
+	for _, rule := range r.disallow {
+		if robotsMatch(rule, requestURI) && len(rule) > longestDisallow {
+			longestDisallow = len(rule)
+		}
+	}
+	return longestDisallow < 0 || longestAllow >= longestDisallow
+}
+
+// robotsMatch matches a robots.txt path rule, where * matches any characters and a
+// trailing $ anchors the end
+func robotsMatch(rule, requestURI string) bool {
+	pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSuffix(rule, "$")), `\*`, ".*")
+	if strings.HasSuffix(rule, "$") {
+		pattern += "$"
+	}
+	matched, _ := regexp.MatchString(pattern, requestURI)
+	return matched
+}
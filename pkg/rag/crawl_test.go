@@ -0,0 +1,254 @@
+package rag
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	content := `# robots.txt
+User-agent: Googlebot
+Disallow: /
+
+User-agent: *
+User-agent: cloudigest
+Disallow: /private/
+Allow: /private/public/
+Disallow:
+Crawl-delay: 1.5
+
+Sitemap: https://docs.example.com/sitemap.xml
+`
+	rules := parseRobots([]byte(content), CrawlerAgent)
+	want := &robotsRules{
+		allow:    []string{"/private/public/"},
+		disallow: []string{"/private/"},
+		delay:    1500 * time.Millisecond,
+		sitemaps: []string{"https://docs.example.com/sitemap.xml"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("parseRobots() = %+v, want %+v", rules, want)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		agent   string
+		want    []string // disallow rules of the group that applies
+	}{
+		{"own group preferred", "User-agent: *\nDisallow: /a\n\nUser-agent: cloudigest\nDisallow: /b\n", CrawlerAgent, []string{"/b"}},
+		{"wildcard group", "User-agent: other\nDisallow: /a\n\nUser-agent: *\nDisallow: /b\n", CrawlerAgent, []string{"/b"}},
+		{"agent matched by substring", "User-agent: cloudigest\nDisallow: /b\n", "cloudigest/1.0", []string{"/b"}},
+		{"no group", "User-agent: other\nDisallow: /a\n", CrawlerAgent, nil},
+		{"rules before any group", "Disallow: /a\n", CrawlerAgent, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRobots([]byte(tt.content), tt.agent).disallow; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRobots() disallow = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := &robotsRules{
+		allow:    []string{"/docs/", "/docs/internal/public", "/*.css$"},
+		disallow: []string{"/docs/internal/", "/search", "/*?print=", "/docs/"},
+	}
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"/docs/intro", true},                  // Allow wins the tie with an equally long Disallow
+		{"/docs/internal/secrets", false},      // the longer Disallow wins
+		{"/docs/internal/public/page", true},   // the longer Allow wins again
+		{"/search?q=eks", false},               // prefix match
+		{"/searching", false},                  // rules are prefixes, not words
+		{"/docs/page?print=1", false},          // wildcard
+		{"/blog/post", true},                   // no rule matches
+		{"/assets/site.css", true},             // anchored Allow
+		{"/docs/internal/site.css?v=2", false}, // the anchor doesn't match
+	}
+	for _, tt := range tests {
+		if got := rules.allowed(tt.uri); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		rule string
+		uri  string
+		want bool
+	}{
+		{"/private", "/private/page", true},
+		{"/private", "/public", false},
+		{"/*.pdf$", "/files/guide.pdf", true},
+		{"/*.pdf$", "/files/guide.pdf?download=1", false},
+		{"/a*b", "/a/x/b/c", true},
+		{"/a.b", "/axb", false},
+		{"", "/anything", true},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.rule, tt.uri); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.rule, tt.uri, got, tt.want)
+		}
+	}
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestParseSitemap(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://docs.example.com/a</loc></url>
+  <url><loc>
+    https://docs.example.com/b
+  </loc><lastmod>2024-01-01</lastmod></url>
+</urlset>`
+	index := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://docs.example.com/sitemap-1.xml.gz</loc></sitemap>
+</sitemapindex>`
+
+	tests := []struct {
+		name     string
+		content  []byte
+		maxSize  int64
+		urls     []string
+		sitemaps []string
+	}{
+		{"urlset", []byte(urlset), 1 << 20, []string{"https://docs.example.com/a", "https://docs.example.com/b"}, nil},
+		{"index", []byte(index), 1 << 20, nil, []string{"https://docs.example.com/sitemap-1.xml.gz"}},
+		{"gzipped", gzipped(t, urlset), 1 << 20, []string{"https://docs.example.com/a", "https://docs.example.com/b"}, nil},
+		{"gzipped over the limit", gzipped(t, urlset), int64(len(urlset) - 1), nil, nil},
+		{"gzipped at the limit", gzipped(t, index), int64(len(index)), nil, []string{"https://docs.example.com/sitemap-1.xml.gz"}},
+		{"corrupt gzip", []byte{0x1f, 0x8b, 0x00, 0x01}, 1 << 20, nil, nil},
+		{"not xml", []byte("<html><body>Not found"), 1 << 20, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, sitemaps := parseSitemap(tt.content, tt.maxSize)
+			if !reflect.DeepEqual(urls, tt.urls) || !reflect.DeepEqual(sitemaps, tt.sitemaps) {
+				t.Errorf("parseSitemap() = %q, %q, want %q, %q", urls, sitemaps, tt.urls, tt.sitemaps)
+			}
+		})
+	}
+}
+
+// fakeSite serves pages from a map, failing for anything else
+func fakeSite(pages map[string]string) FetchFunc {
+	return func(location string) ([]byte, string, error) {
+		content, ok := pages[location]
+		if !ok {
+			return nil, "", fmt.Errorf("404 Not Found")
+		}
+		contentType := "text/html"
+		if strings.HasSuffix(location, ".xml") || strings.HasSuffix(location, ".txt") {
+			contentType = "text/plain"
+		}
+		return []byte(content), contentType, nil
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	site := map[string]string{
+		"https://docs.example.com/robots.txt": "User-agent: *\nDisallow: /guide/drafts/\n",
+		"https://docs.example.com/sitemap.xml": `<urlset><url><loc>https://docs.example.com/guide/from-sitemap</loc></url>` +
+			`<url><loc>https://docs.example.com/blog/out-of-scope</loc></url></urlset>`,
+		"https://docs.example.com/guide/": `<a href="install">Install</a> <a href="drafts/wip">WIP</a>
+			<a href="https://other.example.com/guide/x">Other host</a> <a href="/guide/install#linux">Again</a>
+			<a href="missing">Missing</a>`,
+		"https://docs.example.com/guide/install":       `<a href="install/linux">Linux</a>`,
+		"https://docs.example.com/guide/install/linux": `<a href="deeper">Deeper</a>`,
+		"https://docs.example.com/guide/from-sitemap":  `sitemap page`,
+	}
+
+	result, err := Crawl(CrawlOptions{Start: "https://docs.example.com/guide/", MaxDepth: 2}, fakeSite(site))
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	var pages []string
+	for _, page := range result.Pages {
+		pages = append(pages, fmt.Sprintf("%s@%d", strings.TrimPrefix(page.URL, "https://docs.example.com"), page.Depth))
+	}
+	sort.Strings(pages)
+	want := []string{"/guide/@0", "/guide/from-sitemap@0", "/guide/install/linux@2", "/guide/install@1"}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("Crawl() pages = %v, want %v", pages, want)
+	}
+	if _, ok := result.Failed["https://docs.example.com/guide/missing"]; !ok || len(result.Failed) != 1 {
+		t.Errorf("Crawl() failed = %v, want only /guide/missing", result.Failed)
+	}
+	if result.Disallowed != 1 || result.Truncated {
+		t.Errorf("Crawl() disallowed, truncated = %d, %v, want 1, false", result.Disallowed, result.Truncated)
+	}
+
+	result, err = Crawl(CrawlOptions{Start: "https://docs.example.com/guide/", MaxPages: 2}, fakeSite(site))
+	if err != nil || len(result.Pages) != 2 || !result.Truncated {
+		t.Errorf("Crawl() with MaxPages 2 = %d pages, truncated %v, error %v", len(result.Pages), result.Truncated, err)
+	}
+}
+
+func TestCrawlStart(t *testing.T) {
+	site := map[string]string{
+		"https://example.com/":     `<a href="/">Home</a> <a href="/docs">Docs</a>`,
+		"https://example.com/docs": `<a href="https://example.com">Home</a>`,
+	}
+	result, err := Crawl(CrawlOptions{Start: "https://example.com"}, fakeSite(site))
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	// The bare host is crawled as "/", so links back to the front page aren't fetched again
+	if len(result.Pages) != 2 || result.Pages[0].URL != "https://example.com/" {
+		t.Errorf("Crawl() pages = %+v, want the front page and /docs", result.Pages)
+	}
+
+	for _, start := range []string{"ftp://example.com/", "/docs", "https://"} {
+		if _, err := Crawl(CrawlOptions{Start: start}, fakeSite(site)); err == nil {
+			t.Errorf("Crawl(%q) succeeded, want an error", start)
+		}
+	}
+
+	robots := map[string]string{"https://example.com/robots.txt": "User-agent: *\nDisallow: /\n"}
+	if _, err := Crawl(CrawlOptions{Start: "https://example.com/docs"}, fakeSite(robots)); err == nil {
+		t.Error("Crawl() of a disallowed start page succeeded, want an error")
+	}
+}
+
+func TestInCrawlScope(t *testing.T) {
+	tests := []struct {
+		start    string
+		location string
+		want     bool
+	}{
+		{"https://docs.example.com/guide/", "https://docs.example.com/guide/install", true},
+		{"https://docs.example.com/guide/index.html", "https://DOCS.example.com/guide/install", true},
+		{"https://docs.example.com/guide/", "https://docs.example.com/blog/", false},
+		{"https://docs.example.com/guide/", "https://other.example.com/guide/install", false},
+	}
+	for _, tt := range tests {
+		if got := InCrawlScope(tt.start, tt.location); got != tt.want {
+			t.Errorf("InCrawlScope(%q, %q) = %v, want %v", tt.start, tt.location, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embedding providers
+const (
+	EmbedderOpenAI = "openai" // OpenAI or any server with an OpenAI-compatible embeddings API
+	EmbedderOllama = "ollama" // a local model served by Ollama
+	EmbedderLocal  = "local"  // built-in offline embedder, no model or network needed
+)
+
+// DefaultOllamaURL is where Ollama listens unless configured otherwise
+const DefaultOllamaURL = "http://localhost:11434"
+
+// embedRetries is how many times a rate-limited embedding request is retried, backing off
+// exponentially from one second
+const embedRetries = 3
+
+// defaultLocalDimensions is the vector size of the offline embedder
+const defaultLocalDimensions = 512
+
+// Embedder turns text into vectors. Vectors from different models can't be compared,
+// so the index records Model() and is rebuilt when it changes.
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Limits() BatchLimits
+}
+
+// BatchLimits is how much a provider accepts in a single Embed call. Zero means no limit.
+type BatchLimits struct {
+	Inputs int
+	Tokens int // estimated with estimateTokens
+}
+
+// NewEmbedder creates an embedding provider by name. model and baseURL are optional;
+// apiKey is only used by the openai provider and dimensions only by the local one.
+func NewEmbedder(provider, model, baseURL, apiKey string, dimensions int) (Embedder, error) {
+	switch provider {
+	case EmbedderOpenAI, "":
+		return NewOpenAIEmbedder(apiKey, model, baseURL), nil
+	case EmbedderOllama:
+		if model == "" {
+			return nil, fmt.Errorf("the ollama embedding provider needs a model, e.g. nomic-embed-text")
+		}
+		return NewOllamaEmbedder(baseURL, model), nil
+	case EmbedderLocal:
+		return NewLocalEmbedder(dimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (expected %s, %s or %s)", provider, EmbedderOpenAI, EmbedderOllama, EmbedderLocal)
+	}
+}
+
+// OpenAIEmbedder uses the OpenAI embeddings API, or a compatible server when a base URL is set
+type OpenAIEmbedder struct {
+	client     *openai.Client
+	model      openai.EmbeddingModel
+	missingKey bool
+}
+
+func NewOpenAIEmbedder(apiKey, model, baseURL string) *OpenAIEmbedder {
+	config := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	if model == "" {
+		model = string(openai.AdaEmbeddingV2)
+	}
+
+	return &OpenAIEmbedder{
+		client:     openai.NewClientWithConfig(config),
+		model:      openai.EmbeddingModel(model),
+		missingKey: apiKey == "" && baseURL == "", // compatible servers often need no key
+	}
+}
+
+func (e *OpenAIEmbedder) Model() string {
+	return string(e.model)
+}
+
+// Limits stays below the API's 2048 inputs and 300k tokens per request
+func (e *OpenAIEmbedder) Limits() BatchLimits {
+	return BatchLimits{Inputs: 2048, Tokens: 250000}
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.missingKey {
+		return nil, fmt.Errorf("OpenAI API key not found in configuration; set rag.embeddings.provider to %s or %s to embed without it", EmbedderOllama, EmbedderLocal)
+	}
+
+	var resp openai.EmbeddingResponse
+	var err error
+	for attempt := 0; ; attempt++ {
+		resp, err = e.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+			Input: texts,
+			Model: e.model,
+		})
+		if err == nil || attempt == embedRetries || !retryable(err) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second << attempt):
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
+	}
+
+	// Results carry their input position and aren't guaranteed to be in order
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
+
+// retryable reports whether a request failed because of rate limiting or a server error
+func retryable(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests || apiErr.HTTPStatusCode >= 500
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests || reqErr.HTTPStatusCode >= 500
+	}
+	return false
+}
+
+// OllamaEmbedder uses a local embedding model served by Ollama
+type OllamaEmbedder struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+func NewOllamaEmbedder(baseURL, model string) *OllamaEmbedder {
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+	}
+
+	return &OllamaEmbedder{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		client:  &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (e *OllamaEmbedder) Model() string {
+	return "ollama:" + e.model
+}
+
+// Limits keeps requests small enough for a local model to answer before the timeout
+func (e *OllamaEmbedder) Limits() BatchLimits {
+	return BatchLimits{Inputs: 64}
+}
+
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama at %s: %v", e.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("Ollama returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama response: %v", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+
+	return result.Embeddings, nil
+}
+
+// LocalEmbedder is an offline embedder that hashes words and character trigrams into a
+// fixed-size vector. It captures lexical rather than semantic similarity, so it is
+// mainly useful without network access or an embedding model, alongside BM25.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultLocalDimensions
+	}
+	return &LocalEmbedder{dimensions: dimensions}
+}
+
+func (e *LocalEmbedder) Model() string {
+	return fmt.Sprintf("local-hash-%d", e.dimensions)
+}
+
+func (e *LocalEmbedder) Limits() BatchLimits {
+	return BatchLimits{}
+}
+
+func (e *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *LocalEmbedder) embed(text string) []float32 {
+	counts := make(map[string]int)
+	for _, term := range tokenize(text) {
+		counts[term] += 2 // whole words weigh more than their trigrams
+		padded := []rune(" " + term + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			counts["#"+string(padded[i:i+3])]++
+		}
+	}
+
+	vector := make([]float32, e.dimensions)
+	for feature, count := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+
+		// The sign bit keeps colliding features from only ever adding up
+		weight := float32(1 + math.Log(float64(count)))
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(e.dimensions)] += weight
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
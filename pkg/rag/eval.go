@@ -0,0 +1,197 @@
+package rag
+
+import (
+	"strings"
+)
+
+// EvalCase is a question together with what a good retrieval for it contains
+type EvalCase struct {
+	Question       string
+	Sources        []string // case-insensitive substrings of the names or locations of sources that answer it
+	Keywords       []string // words or phrases the retrieved chunks should contain
+	AnswerKeywords []string // words or phrases a good answer contains; defaults to Keywords
+}
+
+// EvalResult is how retrieval, and generation if it was run, did on one question.
+// Recall counts the expected sources and keywords found among the retrieved chunks. A
+// chunk is relevant when it comes from an expected source or, for questions without
+// expected sources, when it contains one of the keywords.
+type EvalResult struct {
+	Case      EvalCase
+	Retrieved []SearchResult
+	Expected  int
+	Missing   []string // expected sources and keywords that weren't retrieved
+	Rank      int      // rank of the first relevant chunk, or 0 if none was retrieved
+	Answer    string
+	Answered  bool
+	// Answer keywords found in the generated answer, and the ones that weren't
+	AnswerFound   int
+	AnswerMissing []string
+	Err           error
+}
+
+// Recall is the fraction of the expected sources and keywords that were retrieved
+func (e *EvalResult) Recall() float64 {
+	if e.Expected == 0 {
+		return 0
+	}
+	return float64(e.Expected-len(e.Missing)) / float64(e.Expected)
+}
+
+// ReciprocalRank is 1/rank of the first relevant chunk, or 0 if none was retrieved
+func (e *EvalResult) ReciprocalRank() float64 {
+	if e.Rank == 0 {
+		return 0
+	}
+	return 1 / float64(e.Rank)
+}
+
+// AnswerCoverage is the fraction of the answer keywords found in the generated answer
+func (e *EvalResult) AnswerCoverage() float64 {
+	total := e.AnswerFound + len(e.AnswerMissing)
+	if total == 0 {
+		return 0
+	}
+	return float64(e.AnswerFound) / float64(total)
+}
+
+// EvalSummary averages the results of an evaluation
+type EvalSummary struct {
+	Questions      int
+	Failed         int     // questions whose retrieval or generation returned an error
+	Recall         float64 // mean recall@k
+	MRR            float64 // mean reciprocal rank of the first relevant chunk
+	HitRate        float64 // fraction of questions with a relevant chunk in the top k
+	Answered       int     // questions with answer keywords that were answered
+	AnswerCoverage float64 // mean answer keyword coverage over the answered questions
+}
+
+// Evaluate retrieves the top k chunks for a question, and with generate also answers
+// it, and scores the result against what the question expects
+func (r *RAG) Evaluate(c EvalCase, generate bool) EvalResult {
+	result := EvalResult{Case: c, Expected: len(c.Sources) + len(c.Keywords)}
+
+	if generate {
+		answer, err := r.Query(c.Question)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Retrieved = answer.References
+		result.Answer = answer.Text
+		result.Answered = true
+	} else {
+		retrieved, err := r.Search(c.Question)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Retrieved = retrieved
+	}
+
+	for _, source := range c.Sources {
+		found := false
+		for _, chunk := range result.Retrieved {
+			if fromSource(chunk, source) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.Missing = append(result.Missing, "source "+source)
+		}
+	}
+	for _, keyword := range c.Keywords {
+		found := false
+		for _, chunk := range result.Retrieved {
+			if containsFoldString(chunk.Content, keyword) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.Missing = append(result.Missing, "keyword "+keyword)
+		}
+	}
+
+	for i, chunk := range result.Retrieved {
+		if relevant(chunk, c) {
+			result.Rank = i + 1
+			break
+		}
+	}
+
+	if result.Answered {
+		keywords := c.AnswerKeywords
+		if len(keywords) == 0 {
+			keywords = c.Keywords
+		}
+		for _, keyword := range keywords {
+			if containsFoldString(result.Answer, keyword) {
+				result.AnswerFound++
+			} else {
+				result.AnswerMissing = append(result.AnswerMissing, keyword)
+			}
+		}
+	}
+
+	return result
+}
+
+// SummarizeEval averages the scores of the questions that didn't fail
+func SummarizeEval(results []EvalResult) EvalSummary {
+	summary := EvalSummary{Questions: len(results)}
+	var scored int
+	for i := range results {
+		result := &results[i]
+		if result.Err != nil {
+			summary.Failed++
+			continue
+		}
+		scored++
+		summary.Recall += result.Recall()
+		summary.MRR += result.ReciprocalRank()
+		if result.Rank > 0 {
+			summary.HitRate++
+		}
+		if result.Answered && result.AnswerFound+len(result.AnswerMissing) > 0 {
+			summary.Answered++
+			summary.AnswerCoverage += result.AnswerCoverage()
+		}
+	}
+
+	if scored > 0 {
+		summary.Recall /= float64(scored)
+		summary.MRR /= float64(scored)
+		summary.HitRate /= float64(scored)
+	}
+	if summary.Answered > 0 {
+		summary.AnswerCoverage /= float64(summary.Answered)
+	}
+	return summary
+}
+
+func relevant(chunk SearchResult, c EvalCase) bool {
+	if len(c.Sources) > 0 {
+		for _, source := range c.Sources {
+			if fromSource(chunk, source) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, keyword := range c.Keywords {
+		if containsFoldString(chunk.Content, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func fromSource(chunk SearchResult, source string) bool {
+	return containsFoldString(chunk.Source, source) || containsFoldString(chunk.Location, source)
+}
+
+func containsFoldString(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
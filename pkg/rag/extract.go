@@ -0,0 +1,99 @@
+package rag
+
+import (
+	"bytes"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"cloudigest/pkg/pdf"
+)
+
+// Extract turns fetched content into the text that gets chunked and embedded, along
+// with document metadata such as the format and page title. PDF text keeps "[Page N]" markers
+// so chunks can record the pages they come from. The format is taken from the
+// Content-Type when there is one, and otherwise from the file extension or the
+// content itself. Formats that are already text are returned unchanged.
+func Extract(content []byte, contentType, location string) (string, map[string]string, error) {
+	if isPDF(content, contentType, location) {
+		pages, err := pdf.Extract(content)
+		if err != nil {
+			return "", nil, err
+		}
+		return pdf.Format(pages), map[string]string{"format": "pdf", "pages": strconv.Itoa(len(pages))}, nil
+	}
+
+	if isHTML(content, contentType, location) {
+		page, err := ExtractHTML(bytes.NewReader(content))
+		if err != nil {
+			return "", nil, err
+		}
+
+		metadata := map[string]string{"format": "html"}
+		if page.Title != "" {
+			metadata["title"] = page.Title
+		}
+		return page.Markdown, metadata, nil
+	}
+
+	return string(content), map[string]string{"format": textFormat(contentType, location)}, nil
+}
+
+// textFormat classifies text content as markdown, yaml, json or text
+func textFormat(contentType, location string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "text/markdown", "text/x-markdown":
+			return "markdown"
+		case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+			return "yaml"
+		case "application/json":
+			return "json"
+		}
+	}
+
+	switch locationExt(location) {
+	case ".md", ".markdown":
+		return "markdown"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	default:
+		return "text"
+	}
+}
+
+func isPDF(content []byte, contentType, location string) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "application/pdf" {
+		return true
+	}
+	return locationExt(location) == ".pdf" || bytes.HasPrefix(content, []byte("%PDF-"))
+}
+
+func isHTML(content []byte, contentType, location string) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	}
+
+	switch locationExt(location) {
+	case ".html", ".htm", ".xhtml":
+		return true
+	}
+
+	head := strings.ToLower(strings.TrimSpace(string(content[:min(len(content), 512)])))
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html")
+}
+
+// locationExt returns the lower-cased extension of a path, or of the path of a URL so
+// that query strings and fragments are ignored
+func locationExt(location string) string {
+	if IsURL(location) {
+		if u, err := url.Parse(location); err == nil {
+			location = u.Path
+		}
+	}
+	return strings.ToLower(path.Ext(location))
+}
@@ -0,0 +1,135 @@
+package rag
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultInclude matches the local file types that can be ingested
+var DefaultInclude = []string{"*.md", "*.markdown", "*.txt", "*.yaml", "*.yml", "*.json", "*.pdf"}
+
+// LocalFile is a file found under a path source
+type LocalFile struct {
+	Path    string // absolute path
+	RelPath string // slash-separated path relative to the source root
+	ModTime time.Time
+	Size    int64
+}
+
+// ListFiles returns the files under root matching at least one include pattern and
+// no exclude pattern. root may also be a single file, which is returned as is.
+//
+// Patterns are matched against the slash-separated path relative to root. "**"
+// matches any number of directories, and a pattern without a slash matches the file
+// name in any directory, so "*.md" finds Markdown files at every depth.
+func ListFiles(root string, include, exclude []string) ([]LocalFile, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", root, err)
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", root, err)
+	}
+	if !info.IsDir() {
+		return []LocalFile{{Path: root, RelPath: filepath.Base(root), ModTime: info.ModTime(), Size: info.Size()}}, nil
+	}
+
+	if len(include) == 0 {
+		include = DefaultInclude
+	}
+
+	var files []LocalFile
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if p != root && (d.Name() == ".git" || matchAny(exclude, rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !matchAny(include, rel) || matchAny(exclude, rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, LocalFile{Path: p, RelPath: rel, ModTime: info.ModTime(), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %v", root, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].RelPath < files[j].RelPath
+	})
+
+	return files, nil
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated relative path against a glob pattern that may
+// contain "**" segments
+func matchGlob(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	// A trailing slash or "/**" means everything below a directory
+	pattern = strings.TrimSuffix(pattern, "/")
+	if strings.HasSuffix(pattern, "/**") {
+		if matchSegments(strings.Split(strings.TrimSuffix(pattern, "/**"), "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,91 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		// Patterns without a slash match the file name at any depth
+		{"*.md", "README.md", true},
+		{"*.md", "docs/runbooks/restart.md", true},
+		{"*.md", "docs/notes.txt", false},
+		// Patterns with a slash match the whole relative path
+		{"docs/*.md", "docs/intro.md", true},
+		{"docs/*.md", "docs/runbooks/restart.md", false},
+		{"./docs/*.md", "docs/intro.md", true},
+		{"docs/**/*.md", "docs/intro.md", true},
+		{"docs/**/*.md", "docs/a/b/c.md", true},
+		{"docs/**/*.md", "other/a.md", false},
+		{"**/drafts/*", "a/b/drafts/wip.md", true},
+		{"**/drafts/*", "drafts/wip.md", true},
+		// A trailing slash matches the directory itself, so excluding it skips everything
+		// below it while walking; "/**" also matches the paths below it
+		{"archive/", "archive", true},
+		{"archive/", "archive/2023/old.md", false},
+		{"archive/**", "archive/2023/old.md", true},
+		{"archive/**", "archive", true},
+		{"archive/**", "archived/old.md", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"README.md", "notes.txt", "image.png",
+		"docs/intro.md", "docs/config.yaml",
+		"docs/archive/old.md", ".git/HEAD.md",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		root    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{"default include", root, nil, nil, []string{"README.md", "docs/archive/old.md", "docs/config.yaml", "docs/intro.md", "notes.txt"}},
+		{"include", root, []string{"*.md"}, nil, []string{"README.md", "docs/archive/old.md", "docs/intro.md"}},
+		{"exclude directory", root, []string{"*.md"}, []string{"docs/archive/**"}, []string{"README.md", "docs/intro.md"}},
+		{"single file", filepath.Join(root, "image.png"), []string{"*.md"}, nil, []string{"image.png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ListFiles(tt.root, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("ListFiles() error = %v", err)
+			}
+			var got []string
+			for _, file := range files {
+				got = append(got, file.RelPath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ListFiles(filepath.Join(root, "missing"), nil, nil); err == nil {
+		t.Error("ListFiles() of a missing path succeeded, want an error")
+	}
+}
@@ -0,0 +1,121 @@
+package rag
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Filter restricts retrieval to chunks from matching sources. A source matches when it
+// matches every field that is set; within a field, any of the values will do.
+type Filter struct {
+	Types     []string  // source types, e.g. runbook
+	Sources   []string  // case-insensitive substrings of the source name or location
+	Tags      []string  // source tags
+	Providers []string  // aws, azure, gcp, k8s, ...
+	Since     time.Time // sources dated on or after this time
+}
+
+// IsZero reports whether the filter lets everything through
+func (f Filter) IsZero() bool {
+	return len(f.Types) == 0 && len(f.Sources) == 0 && len(f.Tags) == 0 &&
+		len(f.Providers) == 0 && f.Since.IsZero()
+}
+
+// Match reports whether a source passes the filter
+func (f Filter) Match(record *SourceRecord) bool {
+	if len(f.Types) > 0 && !containsFold(f.Types, record.Type) {
+		return false
+	}
+	if len(f.Providers) > 0 && !containsFold(f.Providers, record.Provider) {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		tagged := false
+		for _, tag := range record.Tags {
+			if containsFold(f.Tags, tag) {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	if len(f.Sources) > 0 {
+		name, location := strings.ToLower(record.Name), strings.ToLower(record.Location)
+		found := false
+		for _, source := range f.Sources {
+			source = strings.ToLower(source)
+			if strings.Contains(name, source) || strings.Contains(location, source) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && record.Date().Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Date is when the source's content was last changed: the file modification time for
+// local files, or when it was last indexed otherwise
+func (s *SourceRecord) Date() time.Time {
+	if !s.ModTime.IsZero() {
+		return s.ModTime
+	}
+	return s.UpdatedAt
+}
+
+// providerHosts maps documentation hosts to the provider they cover
+var providerHosts = map[string]string{
+	"aws.amazon.com":          "aws",
+	"docs.aws.amazon.com":     "aws",
+	"cloud.google.com":        "gcp",
+	"learn.microsoft.com":     "azure",
+	"docs.microsoft.com":      "azure",
+	"azure.microsoft.com":     "azure",
+	"kubernetes.io":           "k8s",
+	"helm.sh":                 "k8s",
+	"docs.docker.com":         "docker",
+	"developer.hashicorp.com": "terraform",
+	"registry.terraform.io":   "terraform",
+}
+
+// InferProvider guesses the cloud provider a URL documents from its host, or returns ""
+func InferProvider(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for host != "" {
+		if provider, ok := providerHosts[host]; ok {
+			// Microsoft Learn covers much more than Azure
+			if provider == "azure" && host != "azure.microsoft.com" && !strings.Contains(u.Path, "/azure") {
+				return ""
+			}
+			return provider
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return ""
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,358 @@
+package rag
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// HNSW parameters: neighbours per node on the upper levels (twice as many on level 0),
+// and the size of the candidate list while inserting
+const (
+	hnswM              = 16
+	hnswEfConstruction = 64
+)
+
+// HNSWGraph is a hierarchical navigable small world graph over the chunk vectors, used for
+// approximate nearest neighbour search in large knowledge bases. Nodes are keyed by chunk
+// ID; the vectors stay in the chunks and are attached when the index is loaded.
+type HNSWGraph struct {
+	M              int
+	EfConstruction int
+	Nodes          []HNSWNode
+	Entry          int32 // -1 while the graph is empty
+	MaxLevel       int
+	Deleted        int
+
+	ids     map[string]int32
+	vectors [][]float32
+	norms   []float32
+	rng     *rand.Rand
+}
+
+// HNSWNode is a chunk in the graph with its neighbours on each level, from level 0 up.
+// Removed chunks stay in the graph as tombstones until it is rebuilt. A tombstone keeps
+// its vector, since the chunk it came from is gone, so that it still routes searches.
+type HNSWNode struct {
+	ID      string
+	Links   [][]int32
+	Deleted bool
+	Vector  []float32 // only set on tombstones
+}
+
+// hnswHit is a chunk found by an approximate search
+type hnswHit struct {
+	id         string
+	similarity float32
+}
+
+func newHNSWGraph() *HNSWGraph {
+	return &HNSWGraph{
+		M:              hnswM,
+		EfConstruction: hnswEfConstruction,
+		Entry:          -1,
+		ids:            make(map[string]int32),
+		rng:            rand.New(rand.NewSource(1)),
+	}
+}
+
+// buildHNSWGraph indexes the vectors of all chunks
+func buildHNSWGraph(chunks []Chunk) *HNSWGraph {
+	g := newHNSWGraph()
+	for _, chunk := range chunks {
+		g.add(chunk.ID, chunk.Vector)
+	}
+	return g
+}
+
+// attach restores the vectors of a graph that was read from disk. It reports false if
+// the graph doesn't cover exactly the given chunks, or has so many tombstones that
+// searches would waste time on them, in which case it must be rebuilt.
+func (g *HNSWGraph) attach(chunks []Chunk) bool {
+	if len(g.Nodes)-g.Deleted != len(chunks) || g.stale() {
+		return false
+	}
+
+	g.ids = make(map[string]int32, len(g.Nodes))
+	for i, node := range g.Nodes {
+		g.ids[node.ID] = int32(i)
+	}
+	g.vectors = make([][]float32, len(g.Nodes))
+	g.norms = make([]float32, len(g.Nodes))
+	for _, chunk := range chunks {
+		n, ok := g.ids[chunk.ID]
+		if !ok || g.Nodes[n].Deleted {
+			return false
+		}
+		g.vectors[n] = chunk.Vector
+		g.norms[n] = norm(chunk.Vector)
+	}
+	for n, node := range g.Nodes {
+		if !node.Deleted {
+			continue
+		}
+		// Graphs saved before tombstones kept their vectors can't route around them
+		if len(node.Vector) == 0 {
+			return false
+		}
+		g.vectors[n] = node.Vector
+		g.norms[n] = norm(node.Vector)
+	}
+	g.rng = rand.New(rand.NewSource(int64(len(g.Nodes))))
+
+	return true
+}
+
+// stale reports whether enough chunks were removed that the graph should be rebuilt
+func (g *HNSWGraph) stale() bool {
+	return g.Deleted > 64 && g.Deleted > len(g.Nodes)/4
+}
+
+func (g *HNSWGraph) add(id string, vector []float32) {
+	if n, ok := g.ids[id]; ok {
+		// Same ID means same source, position and content
+		if g.Nodes[n].Deleted {
+			g.Nodes[n].Deleted = false
+			g.Nodes[n].Vector = nil
+			g.Deleted--
+		}
+		g.vectors[n] = vector
+		g.norms[n] = norm(vector)
+		return
+	}
+
+	level := int(math.Floor(-math.Log(1-g.rng.Float64()) / math.Log(float64(g.M))))
+	n := int32(len(g.Nodes))
+	g.Nodes = append(g.Nodes, HNSWNode{ID: id, Links: make([][]int32, level+1)})
+	g.vectors = append(g.vectors, vector)
+	g.norms = append(g.norms, norm(vector))
+	g.ids[id] = n
+
+	if g.Entry < 0 {
+		g.Entry = n
+		g.MaxLevel = level
+		return
+	}
+
+	// Descend greedily to the new node's top level, then connect it on every level below
+	q, qNorm := vector, g.norms[n]
+	entries := []candidate{{g.Entry, g.distance(q, qNorm, g.Entry)}}
+	for l := g.MaxLevel; l > level; l-- {
+		entries = g.searchLayer(q, qNorm, entries, 1, l)
+	}
+	for l := min(level, g.MaxLevel); l >= 0; l-- {
+		found := g.searchLayer(q, qNorm, entries, g.EfConstruction, l)
+		for _, neighbour := range g.selectNeighbours(found, g.M) {
+			g.Nodes[n].Links[l] = append(g.Nodes[n].Links[l], neighbour.node)
+			g.link(neighbour.node, n, l)
+		}
+		entries = found
+	}
+
+	if level > g.MaxLevel {
+		g.MaxLevel = level
+		g.Entry = n
+	}
+}
+
+func (g *HNSWGraph) remove(id string) {
+	if n, ok := g.ids[id]; ok && !g.Nodes[n].Deleted {
+		g.Nodes[n].Deleted = true
+		g.Nodes[n].Vector = g.vectors[n]
+		g.Deleted++
+	}
+}
+
+// search returns up to k chunks closest to the query, examining ef candidates on level 0
+func (g *HNSWGraph) search(query []float32, k, ef int) []hnswHit {
+	if g.Entry < 0 || k <= 0 {
+		return nil
+	}
+
+	qNorm := norm(query)
+	entries := []candidate{{g.Entry, g.distance(query, qNorm, g.Entry)}}
+	for l := g.MaxLevel; l > 0; l-- {
+		entries = g.searchLayer(query, qNorm, entries, 1, l)
+	}
+
+	var hits []hnswHit
+	for _, c := range g.searchLayer(query, qNorm, entries, max(ef, k)+g.Deleted, 0) {
+		if g.Nodes[c.node].Deleted {
+			continue
+		}
+		hits = append(hits, hnswHit{id: g.Nodes[c.node].ID, similarity: 1 - c.dist})
+		if len(hits) == k {
+			break
+		}
+	}
+	return hits
+}
+
+// searchLayer finds the ef nodes closest to q on one level, starting from entries, and
+// returns them closest first
+func (g *HNSWGraph) searchLayer(q []float32, qNorm float32, entries []candidate, ef, level int) []candidate {
+	visited := make([]bool, len(g.Nodes))
+	candidates := &candidateHeap{}
+	results := &candidateHeap{farthestFirst: true}
+	for _, e := range entries {
+		visited[e.node] = true
+		heap.Push(candidates, e)
+		heap.Push(results, e)
+		if results.Len() > ef {
+			heap.Pop(results)
+		}
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && c.dist > results.items[0].dist {
+			break
+		}
+
+		for _, neighbour := range g.Nodes[c.node].Links[level] {
+			if visited[neighbour] {
+				continue
+			}
+			visited[neighbour] = true
+
+			d := g.distance(q, qNorm, neighbour)
+			if results.Len() < ef || d < results.items[0].dist {
+				heap.Push(candidates, candidate{neighbour, d})
+				heap.Push(results, candidate{neighbour, d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := results.items
+	sort.Slice(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+	return found
+}
+
+// selectNeighbours picks up to m of the candidates, which must be sorted closest first,
+// preferring ones that aren't closer to an already selected neighbour than to the node
+// itself so that links spread out in different directions
+func (g *HNSWGraph) selectNeighbours(candidates []candidate, m int) []candidate {
+	var selected, pruned []candidate
+	for _, c := range candidates {
+		if len(selected) == m {
+			break
+		}
+		diverse := true
+		for _, s := range selected {
+			if g.between(c.node, s.node) < c.dist {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c)
+		} else {
+			pruned = append(pruned, c)
+		}
+	}
+
+	for _, c := range pruned {
+		if len(selected) == m {
+			break
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// link adds a link from one node to another, pruning the node's links when it has too many
+func (g *HNSWGraph) link(from, to int32, level int) {
+	links := append(g.Nodes[from].Links[level], to)
+	limit := g.M
+	if level == 0 {
+		limit = 2 * g.M
+	}
+
+	if len(links) > limit {
+		candidates := make([]candidate, len(links))
+		for i, node := range links {
+			candidates[i] = candidate{node, g.between(from, node)}
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+
+		// Keeping the closest is much cheaper than reapplying the selection heuristic
+		links = links[:0]
+		for _, c := range candidates[:limit] {
+			links = append(links, c.node)
+		}
+	}
+	g.Nodes[from].Links[level] = links
+}
+
+// distance is the cosine distance between a query and a node
+func (g *HNSWGraph) distance(q []float32, qNorm float32, node int32) float32 {
+	return cosineDistance(q, qNorm, g.vectors[node], g.norms[node])
+}
+
+// between is the cosine distance between two nodes
+func (g *HNSWGraph) between(a, b int32) float32 {
+	return cosineDistance(g.vectors[a], g.norms[a], g.vectors[b], g.norms[b])
+}
+
+func cosineDistance(a []float32, aNorm float32, b []float32, bNorm float32) float32 {
+	if len(a) != len(b) || aNorm == 0 || bNorm == 0 {
+		return 1
+	}
+
+	// Four accumulators let the additions overlap; this loop dominates indexing time
+	var d0, d1, d2, d3 float32
+	b = b[:len(a)]
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		d0 += a[i] * b[i]
+		d1 += a[i+1] * b[i+1]
+		d2 += a[i+2] * b[i+2]
+		d3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		d0 += a[i] * b[i]
+	}
+	return 1 - (d0+d1+d2+d3)/(aNorm*bNorm)
+}
+
+func norm(v []float32) float32 {
+	var sum float32
+	for _, x := range v {
+		sum += x * x
+	}
+	return sqrt(sum)
+}
+
+type candidate struct {
+	node int32
+	dist float32
+}
+
+// candidateHeap is a min-heap by distance, or a max-heap when farthestFirst is set
+type candidateHeap struct {
+	items         []candidate
+	farthestFirst bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.farthestFirst {
+		return h.items[i].dist > h.items[j].dist
+	}
+	return h.items[i].dist < h.items[j].dist
+}
+
+func (h *candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *candidateHeap) Push(x interface{}) { h.items = append(h.items, x.(candidate)) }
+
+func (h *candidateHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,162 @@
+package rag
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"testing"
+)
+
+// minRecall is the recall@10 the graph must reach against an exhaustive search
+const minRecall = 0.9
+
+func syntheticChunks(n, dimensions int) []Chunk {
+	chunks := make([]Chunk, n)
+	for i, vector := range SyntheticVectors(n, dimensions, 1) {
+		chunks[i] = Chunk{ID: chunkID("test", i, ""), Vector: vector}
+	}
+	return chunks
+}
+
+// graphRecall is the fraction of the exact top k of each query that the graph finds,
+// counting ties with the k-th exact match as found
+func graphRecall(t *testing.T, g *HNSWGraph, chunks []Chunk, queries [][]float32, k int) float64 {
+	t.Helper()
+	live := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		live[chunk.ID] = true
+	}
+
+	var found, total int
+	for _, query := range queries {
+		exact := bruteForceSearch(chunks, query, k)
+		threshold := exact[len(exact)-1]
+		total += len(exact)
+		for _, hit := range g.search(query, k, defaultEfSearch) {
+			if !live[hit.id] {
+				t.Fatalf("search returned removed chunk %s", hit.id)
+			}
+			if hit.similarity >= threshold-1e-5 {
+				found++
+			}
+		}
+	}
+	return float64(found) / float64(total)
+}
+
+func TestHNSWRecall(t *testing.T) {
+	tests := []struct {
+		vectors    int
+		dimensions int
+	}{
+		{500, 32},
+		{5000, 64},
+		{5000, 256},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%dx%d", tt.vectors, tt.dimensions), func(t *testing.T) {
+			vectors := SyntheticVectors(tt.vectors, tt.dimensions, 1)
+			queries := PerturbedQueries(vectors, 100, 2)
+			result := BenchmarkSearch(vectors, queries, 10, defaultEfSearch)
+			if result.Recall < minRecall {
+				t.Errorf("recall@10 = %.3f, want at least %.2f", result.Recall, minRecall)
+			}
+		})
+	}
+}
+
+func TestHNSWTombstonesAfterReload(t *testing.T) {
+	chunks := syntheticChunks(2000, 64)
+	g := buildHNSWGraph(chunks)
+
+	// Remove a fifth of the chunks, fewer than make the graph stale
+	var kept []Chunk
+	for i, chunk := range chunks {
+		if i%5 == 0 {
+			g.remove(chunk.ID)
+		} else {
+			kept = append(kept, chunk)
+		}
+	}
+	if g.stale() {
+		t.Fatalf("graph with %d of %d nodes removed is stale", g.Deleted, len(g.Nodes))
+	}
+
+	var saved bytes.Buffer
+	if err := gob.NewEncoder(&saved).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+	var loaded HNSWGraph
+	if err := gob.NewDecoder(&saved).Decode(&loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.attach(kept) {
+		t.Fatal("attach() = false, want true")
+	}
+
+	queries := PerturbedQueries(SyntheticVectors(2000, 64, 1), 100, 2)
+	if recall := graphRecall(t, &loaded, kept, queries, 10); recall < minRecall {
+		t.Errorf("recall@10 after reload = %.3f, want at least %.2f", recall, minRecall)
+	}
+}
+
+func TestHNSWAttachRebuilds(t *testing.T) {
+	chunks := syntheticChunks(400, 16)
+
+	tests := []struct {
+		name   string
+		remove int
+		strip  bool // drop tombstone vectors, as graphs saved by earlier versions have
+		chunks []Chunk
+		want   bool
+	}{
+		{"intact", 0, false, chunks, true},
+		{"few tombstones", 50, false, chunks[50:], true},
+		{"stale", 150, false, chunks[150:], false},
+		{"tombstones without vectors", 50, true, chunks[50:], false},
+		{"missing chunk", 0, false, chunks[1:], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := buildHNSWGraph(chunks)
+			for _, chunk := range chunks[:tt.remove] {
+				g.remove(chunk.ID)
+			}
+			if tt.strip {
+				for i := range g.Nodes {
+					g.Nodes[i].Vector = nil
+				}
+			}
+			if got := g.attach(tt.chunks); got != tt.want {
+				t.Errorf("attach() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func benchmarkSetup(b *testing.B) ([]Chunk, [][]float32) {
+	b.Helper()
+	chunks := syntheticChunks(10000, 256)
+	vectors := make([][]float32, len(chunks))
+	for i, chunk := range chunks {
+		vectors[i] = chunk.Vector
+	}
+	return chunks, PerturbedQueries(vectors, 100, 2)
+}
+
+func BenchmarkHNSWSearch(b *testing.B) {
+	chunks, queries := benchmarkSetup(b)
+	g := buildHNSWGraph(chunks)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.search(queries[i%len(queries)], 10, defaultEfSearch)
+	}
+}
+
+func BenchmarkBruteForce(b *testing.B) {
+	chunks, queries := benchmarkSetup(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteForceSearch(chunks, queries[i%len(queries)], 10)
+	}
+}
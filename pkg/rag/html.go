@@ -0,0 +1,327 @@
+package rag
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// boilerplateTags are dropped together with everything inside them
+var boilerplateTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Nav: true, atom.Aside: true, atom.Form: true, atom.Iframe: true,
+	atom.Button: true, atom.Select: true, atom.Canvas: true, atom.Head: true,
+}
+
+// boilerplateClasses mark navigation and page chrome in class and id attributes
+var boilerplateClasses = []string{"sidebar", "navbar", "menu", "breadcrumb", "cookie", "footer", "toc"}
+
+var blockTags = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Body: true,
+	atom.Dd: true, atom.Details: true, atom.Dialog: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true, atom.Html: true,
+	atom.Li: true, atom.Main: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Summary: true, atom.Table: true, atom.Ul: true,
+}
+
+// HTMLPage is the readable content extracted from an HTML page
+type HTMLPage struct {
+	Title    string
+	Markdown string
+}
+
+// ExtractHTML converts an HTML page to Markdown, keeping headings, paragraphs, lists,
+// tables and code blocks and dropping scripts, styles, navigation and other page chrome.
+// When the page has a <main> or <article> element only its content is kept.
+func ExtractHTML(r io.Reader) (*HTMLPage, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %v", err)
+	}
+
+	page := &HTMLPage{}
+	if title := findElement(doc, func(n *html.Node) bool { return n.DataAtom == atom.Title }); title != nil {
+		page.Title = collapseSpace(textContent(title))
+	}
+
+	root := findElement(doc, func(n *html.Node) bool {
+		return n.DataAtom == atom.Main || attr(n, "role") == "main"
+	})
+	if root == nil {
+		root = findElement(doc, func(n *html.Node) bool { return n.DataAtom == atom.Article })
+	}
+
+	c := &htmlConverter{inContent: root != nil}
+	if root == nil {
+		root = doc
+	}
+	c.block(root)
+
+	page.Markdown = strings.TrimSpace(c.out.String())
+	if page.Title == "" {
+		if h1 := findElement(root, func(n *html.Node) bool { return n.DataAtom == atom.H1 }); h1 != nil {
+			page.Title = collapseSpace(textContent(h1))
+		}
+	}
+
+	return page, nil
+}
+
+type htmlConverter struct {
+	out       strings.Builder
+	para      strings.Builder
+	inContent bool // rendering a <main> or <article>, where <header> is content rather than chrome
+}
+
+// block renders the children of a block element, gathering runs of inline content into paragraphs
+func (c *htmlConverter) block(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && blockTags[child.DataAtom] {
+			c.flush()
+			c.element(child)
+			continue
+		}
+		c.inline(child, &c.para)
+	}
+	c.flush()
+}
+
+func (c *htmlConverter) element(n *html.Node) {
+	if c.skip(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level, _ := strconv.Atoi(n.Data[1:])
+		if text := inlineText(n); text != "" {
+			c.write(strings.Repeat("#", level) + " " + text)
+		}
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if code != "" {
+			c.write("```" + codeLanguage(n) + "\n" + code + "\n```")
+		}
+	case atom.Ul, atom.Ol:
+		var lines []string
+		listItems(n, 0, &lines)
+		if len(lines) > 0 {
+			c.write(strings.Join(lines, "\n"))
+		}
+	case atom.Table:
+		if rows := tableRows(n); len(rows) > 0 {
+			c.write(strings.Join(rows, "\n"))
+		}
+	case atom.Blockquote:
+		inner := &htmlConverter{inContent: c.inContent}
+		inner.block(n)
+		if text := strings.TrimSpace(inner.out.String()); text != "" {
+			c.write("> " + strings.ReplaceAll(text, "\n", "\n> "))
+		}
+	case atom.Hr:
+	default:
+		c.block(n)
+	}
+}
+
+// skip reports whether an element is boilerplate
+func (c *htmlConverter) skip(n *html.Node) bool {
+	if boilerplateTags[n.DataAtom] || (!c.inContent && (n.DataAtom == atom.Header || n.DataAtom == atom.Footer)) {
+		return true
+	}
+	if _, hidden := attrValue(n, "hidden"); hidden || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	switch attr(n, "role") {
+	case "navigation", "banner", "contentinfo", "search", "complementary":
+		return true
+	}
+
+	for _, name := range strings.Fields(strings.ToLower(attr(n, "class") + " " + attr(n, "id"))) {
+		for _, marker := range boilerplateClasses {
+			if name == marker || strings.HasPrefix(name, marker+"-") || strings.HasSuffix(name, "-"+marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inline appends the text of an inline node to buf, wrapping inline code in backticks
+func (c *htmlConverter) inline(n *html.Node, buf *strings.Builder) {
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if c.skip(n) {
+			return
+		}
+		switch n.DataAtom {
+		case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+			if code := collapseSpace(textContent(n)); code != "" {
+				buf.WriteString("`" + code + "`")
+			}
+			return
+		case atom.Br:
+			buf.WriteString(" ")
+			return
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.inline(child, buf)
+	}
+}
+
+func (c *htmlConverter) flush() {
+	text := collapseSpace(c.para.String())
+	c.para.Reset()
+	if text != "" {
+		c.write(text)
+	}
+}
+
+// write adds a block to the output, separated from the previous one by a blank line
+func (c *htmlConverter) write(block string) {
+	if c.out.Len() > 0 {
+		c.out.WriteString("\n\n")
+	}
+	c.out.WriteString(block)
+}
+
+// listItems renders the items of a list, indenting nested lists
+func listItems(list *html.Node, depth int, lines *[]string) {
+	c := &htmlConverter{inContent: true}
+	number := 1
+	for li := list.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li || c.skip(li) {
+			continue
+		}
+
+		var text strings.Builder
+		var nested []*html.Node
+		for child := li.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && (child.DataAtom == atom.Ul || child.DataAtom == atom.Ol) {
+				nested = append(nested, child)
+				continue
+			}
+			c.inline(child, &text)
+		}
+
+		marker := "- "
+		if list.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		if item := collapseSpace(text.String()); item != "" {
+			*lines = append(*lines, strings.Repeat("  ", depth)+marker+item)
+		}
+		for _, n := range nested {
+			listItems(n, depth+1, lines)
+		}
+	}
+}
+
+// tableRows renders each table row as a line of cells separated by pipes
+func tableRows(table *html.Node) []string {
+	var rows []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom != atom.Tr {
+				walk(child)
+				continue
+			}
+
+			var cells []string
+			for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+					cells = append(cells, inlineText(cell))
+				}
+			}
+			if len(cells) > 0 {
+				rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// codeLanguage reads the language from a language-* class on a <pre> or its <code>
+func codeLanguage(pre *html.Node) string {
+	nodes := []*html.Node{pre}
+	if code := findElement(pre, func(n *html.Node) bool { return n.DataAtom == atom.Code }); code != nil {
+		nodes = append(nodes, code)
+	}
+	for _, n := range nodes {
+		for _, class := range strings.Fields(attr(n, "class")) {
+			if lang, ok := strings.CutPrefix(class, "language-"); ok {
+				return lang
+			}
+			if lang, ok := strings.CutPrefix(class, "lang-"); ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+func inlineText(n *html.Node) string {
+	var buf strings.Builder
+	c := &htmlConverter{inContent: true}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.inline(child, &buf)
+	}
+	return collapseSpace(buf.String())
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var buf strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		buf.WriteString(textContent(child))
+	}
+	return buf.String()
+}
+
+func findElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	value, _ := attrValue(n, key)
+	return value
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
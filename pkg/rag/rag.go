@@ -3,210 +3,828 @@ package rag
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"time"
 
 	anthropic "github.com/liushuangls/go-anthropic/v2"
 	"github.com/sashabaranov/go-openai"
 )
 
 type Document struct {
-	Content string
-	Source  string
-	Type    string // "article", "documentation", "diagram", etc.
+	Content      string
+	Source       string
+	Type         string // "article", "documentation", "diagram", etc.
+	Location     string // URL or path the document was loaded from; defaults to Source
+	Tags         []string
+	Provider     string    // aws, azure, gcp, k8s, ...; inferred from the URL when empty
+	Origin       string    // how the source was added; defaults to OriginConfig
+	ModTime      time.Time // modification time of local files
+	ETag         string    // HTTP validators of URLs, for conditional refetches
+	LastModified string
+	Metadata     map[string]string
+}
+
+// SearchResult is a retrieved chunk with its ranking score. Score is the fused rank
+// score for hybrid retrieval, or the cosine similarity when keyword search is off.
+type SearchResult struct {
+	Document
+	Score        float32
+	Similarity   float32 // cosine similarity to the query
+	KeywordScore float32 // BM25 score, zero if no query term matched
+}
+
+// Stats describes the contents of the knowledge base index
+type Stats struct {
+	Sources        int
+	Chunks         int
+	Words          int
+	Dimensions     int
+	EmbeddingModel string
+	VectorSearch   string // how queries are matched against the vectors
 }
 
 type RAG struct {
-	openaiClient *openai.Client
-	claudeClient *anthropic.Client
-	documents    []Document
-	embeddings   map[string][]float32
-	chunkSize    int
-	maxTokens    int
-	useOpenAI    bool
+	openaiClient     *openai.Client
+	claudeClient     *anthropic.Client
+	embedder         Embedder
+	embedBatchSize   int
+	embedConcurrency int
+	progress         ProgressFunc
+	index            *Index
+	keywords         *keywordIndex
+	positions        map[string]int // chunk position by ID, for graph search results
+	indexDir         string
+	dirty            bool
+	chunkSize        int
+	chunkOverlap     int
+	chunkers         map[string]string // chunker name by source type
+	maxTokens        int
+	topK             int
+	minScore         float32
+	keywordWeight    float64
+	filter           Filter
+	annEnabled       bool
+	annMinChunks     int
+	efSearch         int
+	useOpenAI        bool
 }
 
 func NewRAG(apiKey string) *RAG {
 	return &RAG{
-		openaiClient: openai.NewClient(apiKey),
-		claudeClient: nil,
-		documents:    make([]Document, 0),
-		embeddings:   make(map[string][]float32),
-		chunkSize:    1000,
-		maxTokens:    4000,
-		useOpenAI:    true,
+		openaiClient:     openai.NewClient(apiKey),
+		claudeClient:     nil,
+		embedder:         NewOpenAIEmbedder(apiKey, "", ""),
+		index:            newIndex(string(openai.AdaEmbeddingV2)),
+		chunkSize:        1000,
+		maxTokens:        4000,
+		topK:             3,
+		keywordWeight:    0.5,
+		embedBatchSize:   defaultEmbedBatchSize,
+		embedConcurrency: defaultEmbedConcurrency,
+		annEnabled:       true,
+		annMinChunks:     defaultANNMinChunks,
+		efSearch:         defaultEfSearch,
+		useOpenAI:        true,
 	}
 }
 
 func NewRAGWithClaude(claudeKey string) *RAG {
 	return &RAG{
-		openaiClient: nil,
-		claudeClient: anthropic.NewClient(claudeKey),
-		documents:    make([]Document, 0),
-		embeddings:   make(map[string][]float32),
-		chunkSize:    1000,
-		maxTokens:    4000,
-		useOpenAI:    false,
+		openaiClient:     nil,
+		claudeClient:     anthropic.NewClient(claudeKey),
+		index:            newIndex(""),
+		chunkSize:        1000,
+		maxTokens:        4000,
+		topK:             3,
+		keywordWeight:    0.5,
+		embedBatchSize:   defaultEmbedBatchSize,
+		embedConcurrency: defaultEmbedConcurrency,
+		annEnabled:       true,
+		annMinChunks:     defaultANNMinChunks,
+		efSearch:         defaultEfSearch,
+		useOpenAI:        false,
 	}
 }
 
+// Open loads the persistent index from dir, which is also where Save writes it.
+// An index written with a different format version or embedding model is discarded
+// so that it gets rebuilt.
+func (r *RAG) Open(dir string) error {
+	r.indexDir = dir
+
+	idx, err := loadIndex(dir)
+	if err != nil {
+		return err
+	}
+	r.keywords = nil
+	r.positions = nil
+	if idx == nil || idx.Version != indexVersion || idx.EmbeddingModel != r.EmbeddingModel() {
+		r.index = newIndex(r.EmbeddingModel())
+		r.dirty = idx != nil
+	} else {
+		r.index = idx
+	}
+
+	// The graph is saved with the index; build it if it's missing or out of step
+	switch {
+	case !r.annEnabled && r.index.Graph != nil:
+		r.index.Graph = nil
+		r.dirty = true
+	case r.annEnabled && (r.index.Graph == nil || !r.index.Graph.attach(r.index.Chunks)):
+		r.index.Graph = buildHNSWGraph(r.index.Chunks)
+		r.dirty = r.dirty || len(r.index.Chunks) > 0
+	}
+
+	return nil
+}
+
+// Save writes the index back to the directory given to Open if anything changed
+func (r *RAG) Save() error {
+	if r.indexDir == "" || !r.dirty {
+		return nil
+	}
+	if err := r.index.save(r.indexDir); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
 func (r *RAG) AddDocument(doc Document) error {
-	chunks := r.splitIntoChunks(doc.Content)
+	_, err := r.IndexDocument(doc)
+	return err
+}
+
+// IndexDocument chunks and embeds a document, replacing any chunks previously indexed
+// from the same location. Documents whose content and chunk size are unchanged are
+// skipped; the returned bool reports whether anything was re-embedded.
+func (r *RAG) IndexDocument(doc Document) (bool, error) {
+	changed, errs := r.IndexDocuments([]Document{doc})
+	return changed[0], errs[0]
+}
+
+// pendingDocument is a document whose chunks are waiting to be embedded
+type pendingDocument struct {
+	doc      Document
+	location string
+	hash     string
+	chunker  string
+	pieces   []TextChunk
+	first    int // position of the first piece in the batch of texts to embed
+}
 
-	for _, chunk := range chunks {
-		embedding, err := r.getEmbedding(chunk)
+// IndexDocuments indexes several documents like IndexDocument, embedding the chunks of
+// all of them together in batches. It reports for each document whether it was
+// re-embedded, or the error that kept it from being indexed; a failed document keeps
+// its previous version in the index.
+func (r *RAG) IndexDocuments(docs []Document) ([]bool, []error) {
+	changed := make([]bool, len(docs))
+	errs := make([]error, len(docs))
+
+	pending := make(map[int]*pendingDocument)
+	var texts []string
+	for i, doc := range docs {
+		location := doc.Location
+		if location == "" {
+			location = doc.Source
+		}
+
+		format := doc.Metadata["format"]
+		chunkerName := r.chunkerFor(doc.Type, format)
+		chunker, err := NewChunker(chunkerName, r.chunkSize, r.chunkOverlap)
 		if err != nil {
-			return fmt.Errorf("failed to get embedding: %v", err)
+			errs[i] = err
+			continue
+		}
+
+		if doc.Provider == "" {
+			doc.Provider = InferProvider(location)
+		}
+
+		hash := contentHash(doc.Content)
+		if record, ok := r.index.Sources[location]; ok && record.Hash == hash && r.sameChunking(record) {
+			// A touched file or refetched page with the same content only needs its
+			// timestamps and validators updated
+			if !record.ModTime.Equal(doc.ModTime) {
+				record.ModTime = doc.ModTime
+				r.dirty = true
+			}
+			if IsURL(location) {
+				record.ETag = doc.ETag
+				record.LastModified = doc.LastModified
+				record.CheckedAt = time.Now().UTC()
+				r.dirty = true
+			}
+			r.relabel(record, doc)
+			continue
 		}
 
-		r.documents = append(r.documents, Document{
-			Content: chunk,
-			Source:  doc.Source,
-			Type:    doc.Type,
-		})
-		r.embeddings[chunk] = embedding
+		p := &pendingDocument{doc: doc, location: location, hash: hash, chunker: chunkerName, first: len(texts)}
+		p.pieces = chunker.Split(doc.Content)
+		for _, piece := range p.pieces {
+			texts = append(texts, piece.Content)
+		}
+		pending[i] = p
 	}
 
-	return nil
+	// Embed everything before touching the index so a failure keeps the previous version
+	vectors, err := r.embedAll(texts)
+
+	for i := range docs {
+		p, ok := pending[i]
+		if !ok {
+			continue
+		}
+
+		var chunks []Chunk
+		for j, piece := range p.pieces {
+			embedding := vectors[p.first+j]
+			if embedding == nil {
+				errs[i] = fmt.Errorf("failed to get embedding: %v", err)
+				break
+			}
+
+			metadata := make(map[string]string, len(p.doc.Metadata)+1)
+			for key, value := range p.doc.Metadata {
+				metadata[key] = value
+			}
+			if piece.Section != "" {
+				metadata["section"] = piece.Section
+			}
+			if piece.Pages != "" {
+				metadata["page"] = piece.Pages
+			}
+
+			chunks = append(chunks, Chunk{
+				ID:       chunkID(p.location, j, piece.Content),
+				Source:   p.location,
+				Content:  piece.Content,
+				Metadata: metadata,
+				Vector:   embedding,
+			})
+		}
+		if errs[i] != nil {
+			continue
+		}
+
+		origin := p.doc.Origin
+		if origin == "" {
+			origin = OriginConfig
+		}
+
+		now := time.Now().UTC()
+		var checkedAt time.Time
+		if IsURL(p.location) {
+			checkedAt = now
+		}
+
+		r.index.removeSource(p.location)
+		r.index.addChunks(chunks)
+		r.index.Sources[p.location] = &SourceRecord{
+			Name:         p.doc.Source,
+			Location:     p.location,
+			Type:         p.doc.Type,
+			Tags:         p.doc.Tags,
+			Provider:     p.doc.Provider,
+			Origin:       origin,
+			Hash:         p.hash,
+			ModTime:      p.doc.ModTime,
+			ETag:         p.doc.ETag,
+			LastModified: p.doc.LastModified,
+			Format:       p.doc.Metadata["format"],
+			Chunker:      p.chunker,
+			ChunkSize:    r.chunkSize,
+			Overlap:      r.chunkOverlap,
+			Chunks:       len(chunks),
+			UpdatedAt:    now,
+			CheckedAt:    checkedAt,
+		}
+		changed[i] = true
+		r.changed()
+	}
+
+	return changed, errs
 }
 
-func (r *RAG) Query(question string) (string, error) {
-	questionEmbedding, err := r.getEmbedding(question)
-	if err != nil {
-		return "", fmt.Errorf("failed to get question embedding: %v", err)
+// Relabel updates the name, type, tags and provider of an indexed source from its
+// configuration without re-embedding it. A type that calls for a different chunker
+// makes IsIndexed report false so that the source gets re-indexed.
+func (r *RAG) Relabel(doc Document) {
+	location := doc.Location
+	if location == "" {
+		location = doc.Source
+	}
+	if record, ok := r.index.Sources[location]; ok {
+		if doc.Provider == "" {
+			doc.Provider = InferProvider(location)
+		}
+		r.relabel(record, doc)
 	}
+}
 
-	relevantDocs := r.findRelevantDocuments(questionEmbedding)
+func (r *RAG) relabel(record *SourceRecord, doc Document) {
+	if record.Name == doc.Source && record.Type == doc.Type && record.Provider == doc.Provider &&
+		strings.Join(record.Tags, ",") == strings.Join(doc.Tags, ",") {
+		return
+	}
+	record.Name = doc.Source
+	record.Type = doc.Type
+	record.Tags = doc.Tags
+	record.Provider = doc.Provider
+	r.dirty = true
+}
 
-	context := r.buildContext(relevantDocs)
+// IsIndexed reports whether a source is in the index and was chunked with the current settings
+func (r *RAG) IsIndexed(location string) bool {
+	record, ok := r.index.Sources[location]
+	return ok && r.sameChunking(record)
+}
 
-	return r.generateAnswer(context, question)
+// IsFileCurrent reports whether a local file is indexed with the current settings and
+// hasn't been modified since, so it doesn't need to be read again
+func (r *RAG) IsFileCurrent(location string, modTime time.Time) bool {
+	record, ok := r.index.Sources[location]
+	return ok && r.sameChunking(record) && record.ModTime.Equal(modTime)
 }
 
-func (r *RAG) getEmbedding(text string) ([]float32, error) {
-	// NOTE: We always use OpenAI for embeddings as Claude doesn't have an embeddings API
-	var client *openai.Client
+// Source returns the index record of a source
+func (r *RAG) Source(location string) (SourceRecord, bool) {
+	record, ok := r.index.Sources[location]
+	if !ok {
+		return SourceRecord{}, false
+	}
+	return *record, true
+}
 
-	if r.useOpenAI {
-		client = r.openaiClient
-	} else {
-		// For Claude RAG, we still need to use OpenAI embeddings
-		return nil, fmt.Errorf("Claude mode requires an OpenAI API key for embeddings - this is not implemented yet")
+// MarkChecked records that a URL source was checked and found unchanged without
+// refetching its content, such as when the server answered 304 Not Modified
+func (r *RAG) MarkChecked(location string) {
+	if record, ok := r.index.Sources[location]; ok {
+		record.CheckedAt = time.Now().UTC()
+		r.dirty = true
+	}
+}
+
+// IsDue reports whether a URL source should be checked for changes: it isn't indexed
+// with the current settings, or it was last checked more than interval ago. A zero
+// interval means indexed sources are only checked on refresh.
+func (r *RAG) IsDue(location string, interval time.Duration) bool {
+	record, ok := r.index.Sources[location]
+	if !ok || !r.sameChunking(record) {
+		return true
+	}
+	if interval <= 0 {
+		return false
+	}
+	checked := record.CheckedAt
+	if checked.IsZero() {
+		checked = record.UpdatedAt
+	}
+	return time.Since(checked) >= interval
+}
+
+// chunkerFor picks the chunker for a source: the one configured for its type, or
+// otherwise one suited to its format. PDFs always get the token chunker, the only one
+// that turns their page markers into page numbers.
+func (r *RAG) chunkerFor(docType, format string) string {
+	if format == "pdf" {
+		return ChunkerToken
+	}
+	if name, ok := r.chunkers[docType]; ok {
+		return name
+	}
+
+	switch format {
+	case "markdown", "html":
+		return ChunkerMarkdown
+	case "yaml":
+		return ChunkerYAML
+	default:
+		return ChunkerToken
+	}
+}
+
+// sameChunking reports whether a source was chunked with the current settings
+func (r *RAG) sameChunking(record *SourceRecord) bool {
+	return record.ChunkSize == r.chunkSize &&
+		record.Overlap == r.chunkOverlap &&
+		record.Chunker == r.chunkerFor(record.Type, record.Format)
+}
+
+// PruneSources removes every indexed source of the given origin whose location is
+// not in keep and returns the removed locations
+func (r *RAG) PruneSources(origin string, keep []string) []string {
+	wanted := make(map[string]bool, len(keep))
+	for _, location := range keep {
+		wanted[location] = true
+	}
+
+	var removed []string
+	for location, record := range r.index.Sources {
+		if record.Origin == origin && !wanted[location] {
+			r.index.removeSource(location)
+			removed = append(removed, location)
+		}
+	}
+	if len(removed) > 0 {
+		r.changed()
+	}
+
+	return removed
+}
+
+// RemoveSource drops a source and its chunks from the index
+func (r *RAG) RemoveSource(location string) bool {
+	if _, ok := r.index.Sources[location]; !ok {
+		return false
+	}
+	r.index.removeSource(location)
+	r.changed()
+	return true
+}
+
+// Sources returns the indexed sources ordered by name
+func (r *RAG) Sources() []SourceRecord {
+	sources := make([]SourceRecord, 0, len(r.index.Sources))
+	for _, record := range r.index.Sources {
+		sources = append(sources, *record)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Name != sources[j].Name {
+			return sources[i].Name < sources[j].Name
+		}
+		return sources[i].Location < sources[j].Location
+	})
+	return sources
+}
+
+// Stats summarizes the index
+func (r *RAG) Stats() Stats {
+	stats := Stats{
+		Sources:        len(r.index.Sources),
+		Chunks:         len(r.index.Chunks),
+		EmbeddingModel: r.index.EmbeddingModel,
+	}
+	for _, chunk := range r.index.Chunks {
+		stats.Words += len(strings.Fields(chunk.Content))
+		if stats.Dimensions == 0 {
+			stats.Dimensions = len(chunk.Vector)
+		}
 	}
 
-	resp, err := client.CreateEmbeddings(
-		context.Background(),
-		openai.EmbeddingRequest{
-			Input: []string{text},
-			Model: openai.AdaEmbeddingV2,
-		},
-	)
+	switch graph := r.index.Graph; {
+	case r.useGraph():
+		stats.VectorSearch = fmt.Sprintf("approximate (HNSW graph of %d nodes, %d removed)", len(graph.Nodes)-graph.Deleted, graph.Deleted)
+	case graph != nil:
+		stats.VectorSearch = fmt.Sprintf("exhaustive (HNSW graph is used from %d chunks)", r.annMinChunks)
+	default:
+		stats.VectorSearch = "exhaustive"
+	}
+	return stats
+}
+
+// Settings are the chunking and retrieval settings in effect, for comparing evaluations
+type Settings struct {
+	ChunkSize     int
+	ChunkOverlap  int
+	TopK          int
+	MinScore      float32
+	KeywordWeight float64
+}
 
+// Settings returns the chunking and retrieval settings in effect
+func (r *RAG) Settings() Settings {
+	return Settings{
+		ChunkSize:     r.chunkSize,
+		ChunkOverlap:  r.chunkOverlap,
+		TopK:          r.topK,
+		MinScore:      r.minScore,
+		KeywordWeight: r.keywordWeight,
+	}
+}
+
+// Vectors returns the embedding of every chunk in the index
+func (r *RAG) Vectors() [][]float32 {
+	vectors := make([][]float32, len(r.index.Chunks))
+	for i, chunk := range r.index.Chunks {
+		vectors[i] = chunk.Vector
+	}
+	return vectors
+}
+
+// Answer is a generated answer together with the chunks it was based on. Citations
+// like [2] in Text refer to References[1].
+type Answer struct {
+	Text       string
+	References []SearchResult
+}
+
+func (r *RAG) Query(question string) (*Answer, error) {
+	return r.QueryWith(question, nil)
+}
+
+// Search returns the chunks that would be used as context for a query, most relevant
+// first, without generating an answer
+func (r *RAG) Search(query string) ([]SearchResult, error) {
+	queryEmbedding, err := r.getEmbedding(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query embedding: %v", err)
+	}
+
+	return r.findRelevantDocuments(query, queryEmbedding), nil
+}
+
+func (r *RAG) getEmbedding(text string) ([]float32, error) {
+	if r.embedder == nil {
+		return nil, fmt.Errorf("no embedding provider configured")
+	}
+
+	vectors, err := r.embedder.Embed(context.Background(), []string{text})
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.Data[0].Embedding, nil
+	return vectors[0], nil
+}
+
+// EmbeddingModel names the model of the configured embedder, as recorded in the index
+func (r *RAG) EmbeddingModel() string {
+	if r.embedder == nil {
+		return ""
+	}
+	return r.embedder.Model()
 }
 
-func (r *RAG) splitIntoChunks(text string) []string {
-	words := strings.Fields(text)
-	var chunks []string
-	var currentChunk []string
+// Defaults for approximate nearest neighbour search
+const (
+	defaultANNMinChunks = 1000
+	defaultEfSearch     = 100
+)
+
+// rrfK dampens the contribution of lower ranks in reciprocal rank fusion; 60 is the
+// value from the original paper
+const rrfK = 60
+
+// vectorRanking returns the positions of up to depth chunks with at least minScore
+// similarity to the query, most similar first, together with their similarities. Large
+// indexes are searched approximately through the graph, small ones exhaustively.
+func (r *RAG) vectorRanking(queryEmbedding []float32, depth int, allowed map[string]bool) (map[int]float32, []int) {
+	similarities := make(map[int]float32)
+	var ranking []int
+
+	// A filtered search scans the matching chunks, which the graph can't restrict itself to
+	if allowed == nil && r.useGraph() {
+		if r.positions == nil {
+			r.positions = make(map[string]int, len(r.index.Chunks))
+			for i, chunk := range r.index.Chunks {
+				r.positions[chunk.ID] = i
+			}
+		}
+
+		for _, hit := range r.index.Graph.search(queryEmbedding, depth, r.efSearch) {
+			i, ok := r.positions[hit.id]
+			if !ok {
+				continue
+			}
+			similarities[i] = hit.similarity
+			if hit.similarity >= r.minScore {
+				ranking = append(ranking, i)
+			}
+		}
+		return similarities, ranking
+	}
 
-	for _, word := range words {
-		currentChunk = append(currentChunk, word)
-		if len(currentChunk) >= r.chunkSize {
-			chunks = append(chunks, strings.Join(currentChunk, " "))
-			currentChunk = nil
+	for i, chunk := range r.index.Chunks {
+		if allowed != nil && !allowed[chunk.Source] {
+			continue
+		}
+		similarities[i] = cosineSimilarity(queryEmbedding, chunk.Vector)
+		if similarities[i] >= r.minScore {
+			ranking = append(ranking, i)
 		}
 	}
 
-	if len(currentChunk) > 0 {
-		chunks = append(chunks, strings.Join(currentChunk, " "))
+	// Sort by similarity score (descending)
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return similarities[ranking[i]] > similarities[ranking[j]]
+	})
+	if len(ranking) > depth {
+		ranking = ranking[:depth]
 	}
+	return similarities, ranking
+}
 
-	return chunks
+// useGraph reports whether vector search goes through the approximate graph. Below
+// annMinChunks an exhaustive scan is fast enough and exact.
+func (r *RAG) useGraph() bool {
+	return r.annEnabled && r.index.Graph != nil && len(r.index.Chunks) >= r.annMinChunks
 }
 
-func (r *RAG) findRelevantDocuments(queryEmbedding []float32) []Document {
-	type docScore struct {
-		doc   Document
-		score float32
+// findRelevantDocuments ranks chunks by cosine similarity and by BM25 keyword score
+// and fuses the two rankings with reciprocal rank fusion, weighted by keywordWeight
+func (r *RAG) findRelevantDocuments(query string, queryEmbedding []float32) []SearchResult {
+	// Only the head of each ranking matters for fusion
+	depth := r.topK * 10
+	if depth < 50 {
+		depth = 50
+	}
+
+	// Sources the filter lets through, or nil when there is no filter
+	var allowed map[string]bool
+	if !r.filter.IsZero() {
+		allowed = make(map[string]bool)
+		for location, record := range r.index.Sources {
+			if r.filter.Match(record) {
+				allowed[location] = true
+			}
+		}
 	}
 
-	var scores []docScore
+	similarities, vectorRanking := r.vectorRanking(queryEmbedding, depth, allowed)
 
-	for _, doc := range r.documents {
-		embedding := r.embeddings[doc.Content]
-		similarity := cosineSimilarity(queryEmbedding, embedding)
-		scores = append(scores, docScore{doc: doc, score: similarity})
+	fused := make(map[int]float64)
+	keywordScores := make(map[int]float64)
+	for rank, chunk := range vectorRanking {
+		fused[chunk] += (1 - r.keywordWeight) / float64(rrfK+rank+1)
 	}
+	if r.keywordWeight > 0 {
+		limit := depth
+		if allowed != nil {
+			limit = len(r.index.Chunks)
+		}
 
-	// Sort by similarity score (descending)
-	// Implementation of sorting omitted for brevity
+		rank := 0
+		for _, match := range r.keywordIndex().search(query, limit) {
+			if allowed != nil && !allowed[r.index.Chunks[match.chunk].Source] {
+				continue
+			}
+			// Keyword matches need min_score similarity too, so weak ones stay out of the context
+			similarity, ok := similarities[match.chunk]
+			if !ok {
+				similarity = cosineSimilarity(queryEmbedding, r.index.Chunks[match.chunk].Vector)
+				similarities[match.chunk] = similarity
+			}
+			if similarity < r.minScore {
+				continue
+			}
+			fused[match.chunk] += r.keywordWeight / float64(rrfK+rank+1)
+			keywordScores[match.chunk] = match.score
+			if rank++; rank == depth {
+				break
+			}
+		}
+	}
+
+	var results []SearchResult
+	for i, score := range fused {
+		if score == 0 {
+			continue
+		}
+
+		chunk := r.index.Chunks[i]
+		doc := Document{Content: chunk.Content, Location: chunk.Source, Metadata: chunk.Metadata}
+		if record, ok := r.index.Sources[chunk.Source]; ok {
+			doc.Source = record.Name
+			doc.Type = record.Type
+			doc.Tags = record.Tags
+			doc.Provider = record.Provider
+		}
+
+		result := SearchResult{
+			Document:     doc,
+			Score:        float32(score),
+			Similarity:   similarities[i],
+			KeywordScore: float32(keywordScores[i]),
+		}
+		// Without keyword search the ranking is plain cosine similarity
+		if r.keywordWeight == 0 {
+			result.Score = result.Similarity
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Similarity > results[j].Similarity
+	})
+
+	// Return the top k most relevant chunks
+	if len(results) > r.topK {
+		results = results[:r.topK]
+	}
+
+	return results
+}
 
-	// Return top 3 most relevant documents
-	var result []Document
-	for i := 0; i < len(scores) && i < 3; i++ {
-		result = append(result, scores[i].doc)
+// keywordIndex returns the BM25 index, building it if the chunks changed
+func (r *RAG) keywordIndex() *keywordIndex {
+	if r.keywords == nil {
+		r.keywords = newKeywordIndex(r.index.Chunks)
 	}
+	return r.keywords
+}
 
-	return result
+// changed marks the index as modified so it is saved and derived indexes are rebuilt
+func (r *RAG) changed() {
+	r.dirty = true
+	r.keywords = nil
+	r.positions = nil
+	if r.index.Graph != nil && r.index.Graph.stale() {
+		r.index.Graph = buildHNSWGraph(r.index.Chunks)
+	}
 }
 
-func (r *RAG) buildContext(docs []Document) string {
+func (r *RAG) buildContext(results []SearchResult) string {
 	var context strings.Builder
 
-	context.WriteString("Based on the following information:\n\n")
-	for _, doc := range docs {
-		context.WriteString("Source: " + doc.Source + "\n")
-		context.WriteString(doc.Content + "\n\n")
+	if len(results) == 0 {
+		return "No relevant information was found in the knowledge base; answer from general knowledge and say so."
+	}
+
+	context.WriteString("Based on the following numbered sources:\n\n")
+	for i, result := range results {
+		context.WriteString(fmt.Sprintf("[%d] Source: %s", i+1, result.Source))
+		if section := result.Metadata["section"]; section != "" {
+			context.WriteString(" (" + section + ")")
+		}
+		if page := result.Metadata["page"]; page != "" {
+			context.WriteString(", page " + page)
+		}
+		context.WriteString("\n")
+		context.WriteString(result.Content + "\n\n")
 	}
 
 	return context.String()
 }
 
+// systemPrompt sets the model's role and asks it to cite the numbered context sources
+const systemPrompt = "You are an infrastructure optimization expert. Use the provided context to answer questions about infrastructure, " +
+	"services, and container deployments. Provide clear, actionable recommendations without implementing them directly. " +
+	"Cite the sources you rely on with their numbers in square brackets, e.g. [1] or [2][3], right after the statement they support. " +
+	"Only cite the numbered sources, and don't add a list of references at the end."
+
 func (r *RAG) generateAnswer(ctx, question string) (string, error) {
+	return r.complete(systemPrompt, []message{{content: ctx + "\n\nQuestion: " + question}}, r.maxTokens)
+}
+
+// message is a turn of the conversation sent to the model
+type message struct {
+	assistant bool
+	content   string
+}
+
+// complete sends a system prompt and conversation to the model and returns its reply
+func (r *RAG) complete(system string, messages []message, maxTokens int) (string, error) {
 	if r.useOpenAI {
 		// Use OpenAI
+		chat := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: system}}
+		for _, m := range messages {
+			role := openai.ChatMessageRoleUser
+			if m.assistant {
+				role = openai.ChatMessageRoleAssistant
+			}
+			chat = append(chat, openai.ChatCompletionMessage{Role: role, Content: m.content})
+		}
+
 		resp, err := r.openaiClient.CreateChatCompletion(
 			context.Background(),
 			openai.ChatCompletionRequest{
-				Model: openai.GPT4o,
-				Messages: []openai.ChatCompletionMessage{
-					{
-						Role: openai.ChatMessageRoleSystem,
-						Content: "You are an infrastructure optimization expert. Use the provided context to answer questions about infrastructure, " +
-							"services, and container deployments. Provide clear, actionable recommendations without implementing them directly.",
-					},
-					{
-						Role:    openai.ChatMessageRoleUser,
-						Content: ctx + "\n\nQuestion: " + question,
-					},
-				},
-				MaxTokens: r.maxTokens,
+				Model:     openai.GPT4o,
+				Messages:  chat,
+				MaxTokens: maxTokens,
 			},
 		)
 
 		if err != nil {
 			return "", err
 		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("the model returned no answer")
+		}
 
 		return resp.Choices[0].Message.Content, nil
 	} else {
 		// Use Claude
-		promptText := "You are an infrastructure optimization expert. Use the provided context to answer questions about infrastructure, " +
-			"services, and container deployments. Provide clear, actionable recommendations without implementing them directly.\n\n" +
-			ctx + "\n\nQuestion: " + question
+		var chat []anthropic.Message
+		for _, m := range messages {
+			if m.assistant {
+				chat = append(chat, anthropic.NewAssistantTextMessage(m.content))
+			} else {
+				chat = append(chat, anthropic.NewUserTextMessage(m.content))
+			}
+		}
 
 		resp, err := r.claudeClient.CreateMessages(
 			context.Background(),
 			anthropic.MessagesRequest{
-				Model: anthropic.ModelClaude3Dot7SonnetLatest,
-				Messages: []anthropic.Message{
-					anthropic.NewUserTextMessage(promptText),
-				},
-				MaxTokens: r.maxTokens,
+				Model:     anthropic.ModelClaude3Dot7SonnetLatest,
+				System:    system,
+				Messages:  chat,
+				MaxTokens: maxTokens,
 			},
 		)
 
@@ -223,17 +841,26 @@ func cosineSimilarity(a, b []float32) float32 {
 	var normA float32
 	var normB float32
 
+	// Vectors from different models can't be compared
+	if len(a) != len(b) {
+		return 0
+	}
+
 	for i := range a {
 		dotProduct += a[i] * b[i]
 		normA += a[i] * a[i]
 		normB += b[i] * b[i]
 	}
 
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
 	return dotProduct / (sqrt(normA) * sqrt(normB))
 }
 
 func sqrt(x float32) float32 {
-	return float32(float64(x))
+	return float32(math.Sqrt(float64(x)))
 }
 
 // SetChunkSize sets the chunk size for document splitting
@@ -241,6 +868,85 @@ func (r *RAG) SetChunkSize(size int) {
 	r.chunkSize = size
 }
 
+// SetEmbedder sets the provider used to embed chunks and queries, independently of
+// the model that generates answers. Call it before Open, which discards an index
+// that was built with a different embedding model.
+func (r *RAG) SetEmbedder(embedder Embedder) {
+	r.embedder = embedder
+	if len(r.index.Chunks) == 0 {
+		r.index.EmbeddingModel = r.EmbeddingModel()
+	}
+}
+
+// SetEmbeddingBatchSize sets how many chunks are embedded per request during ingestion.
+// The provider's own limits still apply.
+func (r *RAG) SetEmbeddingBatchSize(size int) {
+	r.embedBatchSize = size
+}
+
+// SetEmbeddingConcurrency sets how many embedding requests run at the same time
+func (r *RAG) SetEmbeddingConcurrency(n int) {
+	r.embedConcurrency = n
+}
+
+// SetProgress sets a function that is told how many chunks have been embedded while
+// documents are indexed
+func (r *RAG) SetProgress(progress ProgressFunc) {
+	r.progress = progress
+}
+
+// SetANN configures approximate nearest neighbour search: whether the graph is kept,
+// the number of chunks from which it is used instead of an exhaustive scan, and how
+// many candidates a search examines (higher is more accurate but slower). Call it
+// before Open, which builds or drops the graph accordingly.
+func (r *RAG) SetANN(enabled bool, minChunks, efSearch int) {
+	r.annEnabled = enabled
+	if minChunks > 0 {
+		r.annMinChunks = minChunks
+	}
+	if efSearch > 0 {
+		r.efSearch = efSearch
+	}
+}
+
+// SetFilter restricts retrieval to chunks from the sources that match the filter
+func (r *RAG) SetFilter(filter Filter) {
+	r.filter = filter
+}
+
+// SetTopK sets how many chunks are retrieved as context for a query
+func (r *RAG) SetTopK(k int) {
+	r.topK = k
+}
+
+// SetMinScore sets the minimum cosine similarity a chunk needs to be retrieved
+func (r *RAG) SetMinScore(score float32) {
+	r.minScore = score
+}
+
+// SetKeywordWeight sets how much BM25 keyword matches count against vector similarity
+// when the two rankings are fused, from 0 (vector search only) to 1 (keyword search only)
+func (r *RAG) SetKeywordWeight(weight float64) {
+	r.keywordWeight = math.Max(0, math.Min(1, weight))
+}
+
+// SetChunkOverlap sets how many tokens consecutive chunks of a document share
+func (r *RAG) SetChunkOverlap(overlap int) {
+	r.chunkOverlap = overlap
+}
+
+// SetChunker selects the chunker used for sources of the given type
+func (r *RAG) SetChunker(docType, name string) error {
+	if _, err := NewChunker(name, r.chunkSize, 0); err != nil {
+		return err
+	}
+	if r.chunkers == nil {
+		r.chunkers = make(map[string]string)
+	}
+	r.chunkers[docType] = name
+	return nil
+}
+
 // SetMaxTokens sets the maximum number of tokens for OpenAI API calls
 func (r *RAG) SetMaxTokens(tokens int) {
 	r.maxTokens = tokens
@@ -0,0 +1,169 @@
+package rag
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// indexVersion is bumped whenever the on-disk index format changes incompatibly
+const indexVersion = 1
+
+// indexFileName is the name of the index file inside the knowledge base directory
+const indexFileName = "index.gob"
+
+// Chunk is a piece of a source document together with its embedding
+type Chunk struct {
+	ID       string
+	Source   string // location of the source the chunk came from
+	Content  string
+	Metadata map[string]string
+	Vector   []float32
+}
+
+// Origins record how a source got into the index, so that syncing the configured
+// sources doesn't remove the ones that were added by hand
+const (
+	OriginConfig  = "config"  // listed under rag.sources
+	OriginManual  = "manual"  // added with `cloudigest kb add`
+	OriginBuiltin = "builtin" // bundled example documents
+	OriginImport  = "import"  // imported from a bundle with `cloudigest kb import`
+)
+
+// SourceRecord tracks an indexed source so unchanged sources can be skipped
+type SourceRecord struct {
+	Name         string
+	Location     string // URL, file path, or a builtin: identifier for bundled documents
+	Type         string
+	Tags         []string
+	Provider     string // aws, azure, gcp, k8s, ...
+	Origin       string
+	Hash         string    // SHA-256 of the content that was embedded
+	ModTime      time.Time // modification time, for local files only
+	ETag         string    // HTTP validators, for URLs only
+	LastModified string
+	Format       string // markdown, html, pdf, yaml, json or text
+	Chunker      string
+	ChunkSize    int
+	Overlap      int
+	Chunks       int
+	UpdatedAt    time.Time // when the content was last embedded
+	CheckedAt    time.Time // when a URL was last fetched or found unchanged
+}
+
+// Index is the persistent store of chunks, metadata and vectors
+type Index struct {
+	Version        int
+	EmbeddingModel string
+	Sources        map[string]*SourceRecord // keyed by location
+	Chunks         []Chunk
+	Graph          *HNSWGraph // approximate nearest neighbour index, nil when disabled
+}
+
+func newIndex(embeddingModel string) *Index {
+	return &Index{
+		Version:        indexVersion,
+		EmbeddingModel: embeddingModel,
+		Sources:        make(map[string]*SourceRecord),
+	}
+}
+
+// IndexPath returns the path of the index file inside a knowledge base directory
+func IndexPath(dir string) string {
+	return filepath.Join(dir, indexFileName)
+}
+
+// loadIndex reads the index from dir. A missing index is not an error and yields an empty index.
+func loadIndex(dir string) (*Index, error) {
+	file, err := os.Open(IndexPath(dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open knowledge base index: %v", err)
+	}
+	defer file.Close()
+
+	var idx Index
+	if err := gob.NewDecoder(file).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("failed to read knowledge base index: %v", err)
+	}
+	if idx.Sources == nil {
+		idx.Sources = make(map[string]*SourceRecord)
+	}
+
+	return &idx, nil
+}
+
+// save writes the index to dir, replacing the previous index atomically
+func (idx *Index) save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create knowledge base directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, indexFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write knowledge base index: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(idx); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write knowledge base index: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write knowledge base index: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), IndexPath(dir)); err != nil {
+		return fmt.Errorf("failed to write knowledge base index: %v", err)
+	}
+	return nil
+}
+
+// removeSource drops a source and all of its chunks
+func (idx *Index) removeSource(location string) {
+	delete(idx.Sources, location)
+
+	kept := idx.Chunks[:0]
+	for _, chunk := range idx.Chunks {
+		if chunk.Source != location {
+			kept = append(kept, chunk)
+		} else if idx.Graph != nil {
+			idx.Graph.remove(chunk.ID)
+		}
+	}
+	idx.Chunks = kept
+}
+
+// addChunks appends chunks and adds their vectors to the graph
+func (idx *Index) addChunks(chunks []Chunk) {
+	idx.Chunks = append(idx.Chunks, chunks...)
+	if idx.Graph != nil {
+		for _, chunk := range chunks {
+			idx.Graph.add(chunk.ID, chunk.Vector)
+		}
+	}
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// chunkID derives a stable identifier from the chunk's source, position and content
+func chunkID(location string, position int, content string) string {
+	sum := sha256.Sum256([]byte(location + "\x00" + strconv.Itoa(position) + "\x00" + content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// IsURL reports whether a source location is an http(s) URL rather than a local path
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
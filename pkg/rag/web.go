@@ -0,0 +1,116 @@
+package rag
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxChunksPerDocument bounds the chunks taken from each extra document, so that one long
+// page can't crowd out the others
+const maxChunksPerDocument = 2
+
+// QueryWith answers a question like Query, with documents that aren't in the index, such
+// as web search results, as additional context. The documents are chunked and embedded
+// for this question only, and up to top_k of their most similar chunks follow the
+// knowledge base chunks in the context and the references.
+func (r *RAG) QueryWith(question string, extra []Document) (*Answer, error) {
+	questionEmbedding, err := r.getEmbedding(question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question embedding: %v", err)
+	}
+
+	results := r.findRelevantDocuments(question, questionEmbedding)
+	if len(extra) > 0 {
+		ranked, err := r.rankDocuments(questionEmbedding, extra)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ranked...)
+	}
+
+	context := r.buildContext(results)
+
+	text, err := r.generateAnswer(context, question)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Answer{Text: text, References: results}, nil
+}
+
+// rankDocuments chunks and embeds documents and returns their chunks that are most
+// similar to the query embedding
+func (r *RAG) rankDocuments(queryEmbedding []float32, docs []Document) ([]SearchResult, error) {
+	type piece struct {
+		doc  int
+		text TextChunk
+	}
+
+	var pieces []piece
+	var texts []string
+	for i, doc := range docs {
+		chunker, err := NewChunker(r.chunkerFor(doc.Type, doc.Metadata["format"]), r.chunkSize, r.chunkOverlap)
+		if err != nil {
+			return nil, err
+		}
+		for _, text := range chunker.Split(doc.Content) {
+			pieces = append(pieces, piece{i, text})
+			texts = append(texts, text.Content)
+		}
+	}
+
+	vectors, err := r.embedAll(texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed documents: %v", err)
+	}
+
+	var results []SearchResult
+	for i, p := range pieces {
+		similarity := cosineSimilarity(queryEmbedding, vectors[i])
+		if similarity < r.minScore {
+			continue
+		}
+
+		doc := docs[p.doc]
+		metadata := make(map[string]string, len(doc.Metadata)+1)
+		for key, value := range doc.Metadata {
+			metadata[key] = value
+		}
+		if p.text.Section != "" {
+			metadata["section"] = p.text.Section
+		}
+		if p.text.Pages != "" {
+			metadata["page"] = p.text.Pages
+		}
+
+		results = append(results, SearchResult{
+			Document: Document{
+				Content:  p.text.Content,
+				Source:   doc.Source,
+				Type:     doc.Type,
+				Location: doc.Location,
+				Tags:     doc.Tags,
+				Provider: doc.Provider,
+				Metadata: metadata,
+			},
+			Score:      similarity,
+			Similarity: similarity,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Similarity > results[j].Similarity })
+
+	// Keep the best chunks of each document, up to top_k in all
+	var ranked []SearchResult
+	taken := make(map[string]int)
+	for _, result := range results {
+		if taken[result.Location] == maxChunksPerDocument {
+			continue
+		}
+		taken[result.Location]++
+		ranked = append(ranked, result)
+		if len(ranked) == r.topK {
+			break
+		}
+	}
+	return ranked, nil
+}
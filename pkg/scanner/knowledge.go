@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits on the knowledge base passages given to the model with each analysis
+const (
+	knowledgePerTopic  = 3
+	maxKnowledgeChunks = 12
+)
+
+// Reference is a knowledge base passage given to the model as a numbered source
+type Reference struct {
+	Source   string // name of the source, with its section if any
+	Location string
+	Content  string
+}
+
+// KnowledgeFunc retrieves the knowledge base passages most relevant to a topic, best first.
+// It may be called concurrently when several hosts are analyzed at once.
+type KnowledgeFunc func(topic string) ([]Reference, error)
+
+// SetKnowledge grounds the analyses in a knowledge base: passages retrieved for each
+// finding category are added to the prompt, and the sources are listed after the analysis
+func (s *Scanner) SetKnowledge(knowledge KnowledgeFunc) {
+	s.knowledge = knowledge
+}
+
+// Topics retrieved for the analyses that don't have deterministic findings to go by
+var (
+	kubernetesTopics = []string{
+		"Kubernetes resource requests and limits",
+		"Kubernetes pod scheduling, placement and availability",
+		"Kubernetes security best practices",
+		"Kubernetes cost optimization and autoscaling",
+	}
+	vmTopics = []string{
+		"virtual machine CPU and memory right-sizing",
+		"Linux server performance tuning and disk usage",
+		"virtual machine cost optimization",
+		"Linux server security hardening and open ports",
+	}
+	fleetTopics = []string{
+		"server fleet consolidation and capacity planning",
+		"configuration drift and OS patching standards",
+		"Linux server security hardening and open ports",
+	}
+)
+
+// retrieve collects the passages for each topic, without duplicates, numbered in the
+// order they are returned
+func (s *Scanner) retrieve(topics []string) ([]Reference, error) {
+	var references []Reference
+	seen := make(map[string]bool)
+	for _, topic := range topics {
+		found, err := s.knowledge(topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search knowledge base for %q: %v", topic, err)
+		}
+		if len(found) > knowledgePerTopic {
+			found = found[:knowledgePerTopic]
+		}
+		for _, reference := range found {
+			key := reference.Location + "\x00" + reference.Content
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			references = append(references, reference)
+			if len(references) == maxKnowledgeChunks {
+				return references, nil
+			}
+		}
+	}
+	return references, nil
+}
+
+// ground adds the knowledge base passages for the topics to a prompt. It returns the prompt
+// unchanged when the scanner has no knowledge base or nothing relevant was found.
+func (s *Scanner) ground(userPrompt string, topics []string) (string, []Reference, error) {
+	if s.knowledge == nil || len(topics) == 0 {
+		return userPrompt, nil, nil
+	}
+
+	references, err := s.retrieve(topics)
+	if err != nil || len(references) == 0 {
+		return userPrompt, nil, err
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(userPrompt)
+	prompt.WriteString("\n\nOur team's documented standards, as numbered knowledge base sources. Base your recommendations " +
+		"on them wherever they apply, prefer them over general advice when the two differ, and cite them with their " +
+		"numbers in square brackets, e.g. [1] or [2][3], right after the recommendation they support. Don't cite " +
+		"sources that aren't relevant.\n\n")
+	for i, reference := range references {
+		fmt.Fprintf(&prompt, "[%d] Source: %s\n%s\n\n", i+1, reference.Source, reference.Content)
+	}
+	return prompt.String(), references, nil
+}
+
+// withSources appends the numbered list of knowledge base sources to an analysis
+func withSources(analysis string, references []Reference) string {
+	if len(references) == 0 {
+		return analysis
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(analysis, "\n"))
+	b.WriteString("\n\nKnowledge base sources:\n")
+	for i, reference := range references {
+		fmt.Fprintf(&b, "[%d] %s", i+1, reference.Source)
+		if reference.Location != "" {
+			fmt.Fprintf(&b, " (%s)", reference.Location)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// analyze grounds the prompt in the knowledge base, if any, and completes it
+func (s *Scanner) analyze(systemPrompt, userPrompt string, topics []string) (string, error) {
+	userPrompt, references, err := s.ground(userPrompt, topics)
+	if err != nil {
+		return "", err
+	}
+
+	analysis, err := s.complete(systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	return withSources(analysis, references), nil
+}
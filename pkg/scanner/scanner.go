@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	anthropic "github.com/liushuangls/go-anthropic/v2"
 	"github.com/sashabaranov/go-openai"
@@ -25,6 +27,7 @@ type Scanner struct {
 	claudeClient *anthropic.Client
 	maxTokens    int
 	useOpenAI    bool
+	knowledge    KnowledgeFunc
 }
 
 func NewScanner(apiKey string) *Scanner {
@@ -122,69 +125,23 @@ func (s *Scanner) ScanKubernetesCluster(kubeconfig string) (string, error) {
 }
 
 func (s *Scanner) analyzeClusterState(clusterInfo string) (string, error) {
-	if s.useOpenAI {
-		// Use OpenAI
-		resp, err := s.openaiClient.CreateChatCompletion(
-			context.Background(),
-			openai.ChatCompletionRequest{
-				Model: openai.GPT4o,
-				Messages: []openai.ChatCompletionMessage{
-					{
-						Role: openai.ChatMessageRoleSystem,
-						Content: "You are a Kubernetes infrastructure expert. Analyze the cluster state and provide " +
-							"detailed recommendations for optimization, focusing on resource utilization, " +
-							"scalability, and best practices.",
-					},
-					{
-						Role: openai.ChatMessageRoleUser,
-						Content: fmt.Sprintf("Please analyze this Kubernetes cluster state and provide insights about:\n"+
-							"1. Resource utilization and allocation\n"+
-							"2. Pod distribution and placement\n"+
-							"3. Potential bottlenecks or issues\n"+
-							"4. Security considerations\n"+
-							"5. Optimization recommendations\n\n"+
-							"Cluster state:\n%s", clusterInfo),
-					},
-				},
-				MaxTokens: s.maxTokens,
-			},
-		)
-
-		if err != nil {
-			return "", fmt.Errorf("failed to analyze cluster state: %v", err)
-		}
-
-		return resp.Choices[0].Message.Content, nil
-	} else {
-		// Use Claude
-		promptText := "You are a Kubernetes infrastructure expert. Analyze the cluster state and provide " +
-			"detailed recommendations for optimization, focusing on resource utilization, " +
-			"scalability, and best practices.\n\n" +
-			fmt.Sprintf("Please analyze this Kubernetes cluster state and provide insights about:\n"+
-				"1. Resource utilization and allocation\n"+
-				"2. Pod distribution and placement\n"+
-				"3. Potential bottlenecks or issues\n"+
-				"4. Security considerations\n"+
-				"5. Optimization recommendations\n\n"+
-				"Cluster state:\n%s", clusterInfo)
-
-		resp, err := s.claudeClient.CreateMessages(
-			context.Background(),
-			anthropic.MessagesRequest{
-				Model: anthropic.ModelClaude3Dot7SonnetLatest,
-				Messages: []anthropic.Message{
-					anthropic.NewUserTextMessage(promptText),
-				},
-				MaxTokens: s.maxTokens,
-			},
-		)
-
-		if err != nil {
-			return "", fmt.Errorf("failed to analyze cluster state: %v", err)
-		}
-
-		return resp.GetFirstContentText(), nil
+	systemPrompt := "You are a Kubernetes infrastructure expert. Analyze the cluster state and provide " +
+		"detailed recommendations for optimization, focusing on resource utilization, " +
+		"scalability, and best practices."
+	userPrompt := fmt.Sprintf("Please analyze this Kubernetes cluster state and provide insights about:\n"+
+		"1. Resource utilization and allocation\n"+
+		"2. Pod distribution and placement\n"+
+		"3. Potential bottlenecks or issues\n"+
+		"4. Security considerations\n"+
+		"5. Optimization recommendations\n\n"+
+		"Cluster state:\n%s", clusterInfo)
+
+	analysis, err := s.analyze(systemPrompt, userPrompt, kubernetesTopics)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze cluster state: %v", err)
 	}
+
+	return analysis, nil
 }
 
 func (s *Scanner) ScanVirtualMachine(vmInfo map[string]interface{}) (string, error) {
@@ -194,67 +151,22 @@ func (s *Scanner) ScanVirtualMachine(vmInfo map[string]interface{}) (string, err
 		return "", fmt.Errorf("failed to marshal VM info: %v", err)
 	}
 
-	if s.useOpenAI {
-		// Use OpenAI
-		resp, err := s.openaiClient.CreateChatCompletion(
-			context.Background(),
-			openai.ChatCompletionRequest{
-				Model: openai.GPT4o,
-				Messages: []openai.ChatCompletionMessage{
-					{
-						Role: openai.ChatMessageRoleSystem,
-						Content: "You are a virtual infrastructure expert. Analyze the VM configuration and metrics " +
-							"to provide optimization recommendations.",
-					},
-					{
-						Role: openai.ChatMessageRoleUser,
-						Content: fmt.Sprintf("Please analyze this VM configuration and provide insights about:\n"+
-							"1. Resource allocation and utilization\n"+
-							"2. Performance metrics\n"+
-							"3. Cost optimization opportunities\n"+
-							"4. Security considerations\n"+
-							"5. Recommendations for improvement\n\n"+
-							"VM configuration:\n%s", string(vmInfoJSON)),
-					},
-				},
-				MaxTokens: s.maxTokens,
-			},
-		)
-
-		if err != nil {
-			return "", fmt.Errorf("failed to analyze VM: %v", err)
-		}
-
-		return resp.Choices[0].Message.Content, nil
-	} else {
-		// Use Claude
-		promptText := "You are a virtual infrastructure expert. Analyze the VM configuration and metrics " +
-			"to provide optimization recommendations.\n\n" +
-			fmt.Sprintf("Please analyze this VM configuration and provide insights about:\n"+
-				"1. Resource allocation and utilization\n"+
-				"2. Performance metrics\n"+
-				"3. Cost optimization opportunities\n"+
-				"4. Security considerations\n"+
-				"5. Recommendations for improvement\n\n"+
-				"VM configuration:\n%s", string(vmInfoJSON))
-
-		resp, err := s.claudeClient.CreateMessages(
-			context.Background(),
-			anthropic.MessagesRequest{
-				Model: anthropic.ModelClaude3Dot7SonnetLatest,
-				Messages: []anthropic.Message{
-					anthropic.NewUserTextMessage(promptText),
-				},
-				MaxTokens: s.maxTokens,
-			},
-		)
-
-		if err != nil {
-			return "", fmt.Errorf("failed to analyze VM: %v", err)
-		}
-
-		return resp.GetFirstContentText(), nil
+	systemPrompt := "You are a virtual infrastructure expert. Analyze the VM configuration and metrics " +
+		"to provide optimization recommendations."
+	userPrompt := fmt.Sprintf("Please analyze this VM configuration and provide insights about:\n"+
+		"1. Resource allocation and utilization\n"+
+		"2. Performance metrics\n"+
+		"3. Cost optimization opportunities\n"+
+		"4. Security considerations\n"+
+		"5. Recommendations for improvement\n\n"+
+		"VM configuration:\n%s", string(vmInfoJSON))
+
+	analysis, err := s.analyze(systemPrompt, userPrompt, vmTopics)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze VM: %v", err)
 	}
+
+	return analysis, nil
 }
 
 func (s *Scanner) GenerateRecommendations(clusterAnalysis, vmAnalysis string) (string, error) {
@@ -326,3 +238,111 @@ func (s *Scanner) GenerateRecommendations(clusterAnalysis, vmAnalysis string) (s
 		return resp.GetFirstContentText(), nil
 	}
 }
+
+// ScanCloudInventory analyzes normalized cloud resources and the deterministic findings raised
+// against them, with a focus on cost optimization. Topics, one per finding category, are
+// looked up in the knowledge base when the scanner has one.
+func (s *Scanner) ScanCloudInventory(inventory map[string]interface{}, topics []string) (string, error) {
+	inventoryJSON, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cloud inventory: %v", err)
+	}
+
+	systemPrompt := "You are a cloud infrastructure and FinOps expert. Analyze the cloud resource inventory " +
+		"and the automated findings to provide cost and configuration optimization recommendations."
+	userPrompt := fmt.Sprintf("Please analyze this cloud inventory and provide insights about:\n"+
+		"1. Idle, orphaned or over-provisioned resources\n"+
+		"2. Instance type and storage tier right-sizing\n"+
+		"3. Purchasing options (reserved, savings plans, spot/preemptible)\n"+
+		"4. Security considerations\n"+
+		"5. Prioritized recommendations with estimated impact\n\n"+
+		"Cloud inventory:\n%s", string(inventoryJSON))
+
+	analysis, err := s.analyze(systemPrompt, userPrompt, topics)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze cloud inventory: %v", err)
+	}
+
+	return analysis, nil
+}
+
+// AnalyzeFleet summarizes the per-host VM analyses of a group of hosts into fleet-level recommendations
+func (s *Scanner) AnalyzeFleet(fleetInfo []map[string]interface{}, hostAnalyses map[string]string) (string, error) {
+	fleetInfoJSON, err := json.MarshalIndent(fleetInfo, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fleet info: %v", err)
+	}
+
+	hosts := make([]string, 0, len(hostAnalyses))
+	for host := range hostAnalyses {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var analyses strings.Builder
+	for _, host := range hosts {
+		analyses.WriteString("Host: " + host + "\n" + hostAnalyses[host] + "\n\n")
+	}
+
+	systemPrompt := "You are a virtual infrastructure expert. Analyze a fleet of VMs as a whole and " +
+		"identify patterns, outliers and consolidation opportunities across hosts."
+	userPrompt := fmt.Sprintf("Please analyze this fleet of VMs and provide insights about:\n"+
+		"1. Hosts that are over- or under-provisioned relative to the rest of the fleet\n"+
+		"2. Configuration drift (OS versions, kernels, exposed ports)\n"+
+		"3. Consolidation and cost optimization opportunities\n"+
+		"4. Fleet-wide security considerations\n"+
+		"5. Prioritized recommendations\n\n"+
+		"Fleet summary:\n%s\n\n"+
+		"Per-host analyses:\n%s", string(fleetInfoJSON), analyses.String())
+
+	analysis, err := s.analyze(systemPrompt, userPrompt, fleetTopics)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze fleet: %v", err)
+	}
+
+	return analysis, nil
+}
+
+// complete sends a single-turn prompt to whichever model the scanner was created with
+func (s *Scanner) complete(systemPrompt, userPrompt string) (string, error) {
+	if s.useOpenAI {
+		resp, err := s.openaiClient.CreateChatCompletion(
+			context.Background(),
+			openai.ChatCompletionRequest{
+				Model: openai.GPT4o,
+				Messages: []openai.ChatCompletionMessage{
+					{
+						Role:    openai.ChatMessageRoleSystem,
+						Content: systemPrompt,
+					},
+					{
+						Role:    openai.ChatMessageRoleUser,
+						Content: userPrompt,
+					},
+				},
+				MaxTokens: s.maxTokens,
+			},
+		)
+		if err != nil {
+			return "", err
+		}
+
+		return resp.Choices[0].Message.Content, nil
+	}
+
+	resp, err := s.claudeClient.CreateMessages(
+		context.Background(),
+		anthropic.MessagesRequest{
+			Model: anthropic.ModelClaude3Dot7SonnetLatest,
+			Messages: []anthropic.Message{
+				anthropic.NewUserTextMessage(systemPrompt + "\n\n" + userPrompt),
+			},
+			MaxTokens: s.maxTokens,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.GetFirstContentText(), nil
+}
@@ -0,0 +1,18 @@
+package search
+
+import "context"
+
+// Result is a web page returned by a search
+type Result struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// Provider runs web searches
+type Provider interface {
+	// Name identifies the provider in messages
+	Name() string
+	// Search returns up to n results for a query, best first
+	Search(ctx context.Context, query string, n int) ([]Result, error)
+}
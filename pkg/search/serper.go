@@ -0,0 +1,94 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSerperURL is the Serper API endpoint used when no base URL is configured
+const DefaultSerperURL = "https://google.serper.dev"
+
+// SerperProvider searches Google through the Serper API (serper.dev)
+type SerperProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewSerper creates a Serper provider. The base URL defaults to DefaultSerperURL and can
+// point at a proxy or a local stand-in.
+func NewSerper(apiKey, baseURL string) *SerperProvider {
+	if baseURL == "" {
+		baseURL = DefaultSerperURL
+	}
+	return &SerperProvider{
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *SerperProvider) Name() string {
+	return "Serper"
+}
+
+type serperRequest struct {
+	Query string `json:"q"`
+	Num   int    `json:"num,omitempty"`
+}
+
+type serperResponse struct {
+	Organic []struct {
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		Snippet string `json:"snippet"`
+	} `json:"organic"`
+}
+
+func (p *SerperProvider) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	body, err := json.Marshal(serperRequest{Query: query, Num: n})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-KEY", p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("search failed with status %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var decoded serperResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode search results: %v", err)
+	}
+
+	var results []Result
+	for _, organic := range decoded.Organic {
+		if organic.Link == "" {
+			continue
+		}
+		results = append(results, Result{Title: organic.Title, URL: organic.Link, Snippet: organic.Snippet})
+		if n > 0 && len(results) == n {
+			break
+		}
+	}
+	return results, nil
+}
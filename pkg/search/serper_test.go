@@ -0,0 +1,89 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSerperSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/search" {
+			t.Errorf("request = %s %s, want POST /search", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("X-API-KEY"); got != "test-key" {
+			t.Errorf("X-API-KEY = %q, want %q", got, "test-key")
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		if want := map[string]interface{}{"q": "eks upgrade", "num": float64(2)}; !reflect.DeepEqual(body, want) {
+			t.Errorf("request body = %v, want %v", body, want)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"searchParameters": {"q": "eks upgrade"},
+			"organic": [
+				{"title": "Updating a cluster", "link": "https://docs.aws.amazon.com/eks/latest/userguide/update-cluster.html", "snippet": "Update the control plane first."},
+				{"title": "No link", "snippet": "skipped"},
+				{"title": "EKS versions", "link": "https://docs.aws.amazon.com/eks/latest/userguide/kubernetes-versions.html", "snippet": "Standard support lasts 14 months."},
+				{"title": "Over the limit", "link": "https://example.com/third", "snippet": "dropped"}
+			]
+		}`))
+	}))
+	defer srv.Close()
+
+	results, err := NewSerper("test-key", srv.URL+"/").Search(context.Background(), "eks upgrade", 2)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	want := []Result{
+		{Title: "Updating a cluster", URL: "https://docs.aws.amazon.com/eks/latest/userguide/update-cluster.html", Snippet: "Update the control plane first."},
+		{Title: "EKS versions", URL: "https://docs.aws.amazon.com/eks/latest/userguide/kubernetes-versions.html", Snippet: "Standard support lasts 14 months."},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Search() = %+v, want %+v", results, want)
+	}
+}
+
+func TestSerperSearchErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"message": "Unauthorized."}`, "Unauthorized"},
+		{"rate limited", http.StatusTooManyRequests, "slow down", "429"},
+		{"server error", http.StatusInternalServerError, "", "500"},
+		{"invalid json", http.StatusOK, "{not json", "failed to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			results, err := NewSerper("test-key", srv.URL).Search(context.Background(), "query", 3)
+			if err == nil {
+				t.Fatalf("Search() = %+v, want an error", results)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Search() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,507 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Finding is the result of a deterministic check against a single resource
+type Finding struct {
+	Severity string `json:"severity"` // "high", "medium" or "low"
+	Rule     string `json:"rule"`
+	Address  string `json:"address"`
+	Message  string `json:"message"`
+	Location string `json:"location,omitempty"`
+
+	attribute string // path of the offending attribute, for locating it in source
+}
+
+// sensitivePorts are services that should never be reachable from the whole internet
+var sensitivePorts = map[int]string{
+	22: "SSH", 23: "Telnet", 445: "SMB", 1433: "MSSQL", 1521: "Oracle", 2375: "Docker",
+	3306: "MySQL", 3389: "RDP", 5432: "PostgreSQL", 5601: "Kibana", 6379: "Redis",
+	9200: "Elasticsearch", 11211: "Memcached", 27017: "MongoDB",
+}
+
+var (
+	// awsSizePattern matches the size suffix of AWS instance types, e.g. 8xlarge in m5.8xlarge
+	awsSizePattern = regexp.MustCompile(`\.(\d+)xlarge$`)
+	// gcpVCPUPattern and azureVCPUPattern match the vCPU count in GCP machine types
+	// (n2-standard-64) and Azure sizes (Standard_D64s_v5)
+	gcpVCPUPattern   = regexp.MustCompile(`-(\d+)$`)
+	azureVCPUPattern = regexp.MustCompile(`^Standard_[A-Z]+(\d+)`)
+)
+
+// oversizedVCPUs is the vCPU count above which an instance type is flagged for review
+const oversizedVCPUs = 32
+
+// Check runs the deterministic checks against every resource. Resources that are
+// planned for deletion only, and data sources, are skipped.
+func Check(resources []Resource) []Finding {
+	var findings []Finding
+	for _, r := range resources {
+		if r.Mode == "data" || r.Values == nil || isDeleteOnly(r.Actions) {
+			continue
+		}
+		var resourceFindings []Finding
+		resourceFindings = append(resourceFindings, checkPublicS3(r)...)
+		resourceFindings = append(resourceFindings, checkOpenIngress(r)...)
+		resourceFindings = append(resourceFindings, checkEncryption(r)...)
+		resourceFindings = append(resourceFindings, checkTags(r)...)
+		resourceFindings = append(resourceFindings, checkInstanceSize(r)...)
+		for _, f := range resourceFindings {
+			f.Location = r.attributeLocation(f.attribute)
+			findings = append(findings, f)
+		}
+	}
+
+	sortFindings(findings)
+	return findings
+}
+
+// sortFindings orders findings by severity, keeping the original order within each severity
+func sortFindings(findings []Finding) {
+	severityRank := map[string]int{"high": 0, "medium": 1, "low": 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] < severityRank[findings[j].Severity]
+	})
+}
+
+func checkPublicS3(r Resource) []Finding {
+	var findings []Finding
+	add := func(severity, attribute, message string) {
+		findings = append(findings, Finding{Severity: severity, Rule: "s3-public-access", Address: r.Address, Message: message, attribute: attribute})
+	}
+
+	switch r.Type {
+	case "aws_s3_bucket", "aws_s3_bucket_acl":
+		if acl := stringValue(r.Values, "acl"); acl == "public-read" || acl == "public-read-write" {
+			add("high", "acl", fmt.Sprintf("bucket ACL %q grants public access", acl))
+		}
+	case "aws_s3_bucket_public_access_block", "aws_s3_account_public_access_block":
+		for _, key := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+			if value, ok := boolValue(r.Values, key); ok && !value {
+				add("high", key, fmt.Sprintf("%s is disabled", key))
+			}
+		}
+	case "aws_s3_bucket_policy":
+		if policyAllowsPublic(stringValue(r.Values, "policy")) {
+			add("high", "policy", "bucket policy allows access to Principal \"*\" without conditions")
+		}
+	}
+
+	return findings
+}
+
+// policyAllowsPublic reports whether an IAM policy document has an unconditional Allow for everyone
+func policyAllowsPublic(policy string) bool {
+	var doc struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if policy == "" || json.Unmarshal([]byte(policy), &doc) != nil {
+		return false
+	}
+
+	// Statement may be a single object or a list
+	var statements []map[string]interface{}
+	if json.Unmarshal(doc.Statement, &statements) != nil {
+		var single map[string]interface{}
+		if json.Unmarshal(doc.Statement, &single) != nil {
+			return false
+		}
+		statements = append(statements, single)
+	}
+
+	for _, st := range statements {
+		if st["Effect"] != "Allow" || st["Condition"] != nil {
+			continue
+		}
+		switch principal := st["Principal"].(type) {
+		case string:
+			if principal == "*" {
+				return true
+			}
+		case map[string]interface{}:
+			if aws, ok := principal["AWS"].(string); ok && aws == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkOpenIngress(r Resource) []Finding {
+	var findings []Finding
+	add := func(attribute string, fromPort, toPort int, protocol, source string) {
+		severity, exposure := ingressSeverity(fromPort, toPort, protocol)
+		findings = append(findings, Finding{
+			Severity:  severity,
+			Rule:      "open-ingress",
+			Address:   r.Address,
+			Message:   fmt.Sprintf("ingress from %s allows %s", source, exposure),
+			attribute: attribute,
+		})
+	}
+
+	switch r.Type {
+	case "aws_security_group":
+		for i, rule := range listValue(r.Values, "ingress") {
+			rule, _ := rule.(map[string]interface{})
+			if source := openCIDR(rule); source != "" {
+				add(fmt.Sprintf("ingress.%d", i), intValue(rule, "from_port"), intValue(rule, "to_port"), stringValue(rule, "protocol"), source)
+			}
+		}
+	case "aws_security_group_rule":
+		if stringValue(r.Values, "type") == "ingress" {
+			if source := openCIDR(r.Values); source != "" {
+				add(cidrAttribute(source), intValue(r.Values, "from_port"), intValue(r.Values, "to_port"), stringValue(r.Values, "protocol"), source)
+			}
+		}
+	case "aws_vpc_security_group_ingress_rule":
+		cidr4, cidr6 := stringValue(r.Values, "cidr_ipv4"), stringValue(r.Values, "cidr_ipv6")
+		if cidr4 == "0.0.0.0/0" || cidr6 == "::/0" {
+			attribute := "cidr_ipv4"
+			if cidr4 != "0.0.0.0/0" {
+				attribute = "cidr_ipv6"
+			}
+			add(attribute, intValue(r.Values, "from_port"), intValue(r.Values, "to_port"), stringValue(r.Values, "ip_protocol"), cidr4+cidr6)
+		}
+	case "google_compute_firewall":
+		if stringValue(r.Values, "direction") == "EGRESS" {
+			break
+		}
+		for _, source := range listValue(r.Values, "source_ranges") {
+			if source != "0.0.0.0/0" && source != "::/0" {
+				continue
+			}
+			for i, allow := range listValue(r.Values, "allow") {
+				allow, _ := allow.(map[string]interface{})
+				attribute := fmt.Sprintf("allow.%d", i)
+				ports := listValue(allow, "ports")
+				if len(ports) == 0 {
+					add(attribute, 0, 65535, stringValue(allow, "protocol"), source.(string))
+				}
+				for _, port := range ports {
+					from, to := parsePortRange(fmt.Sprint(port))
+					add(attribute, from, to, stringValue(allow, "protocol"), source.(string))
+				}
+			}
+		}
+	case "azurerm_network_security_rule":
+		source := stringValue(r.Values, "source_address_prefix")
+		if stringValue(r.Values, "direction") == "Inbound" && stringValue(r.Values, "access") == "Allow" &&
+			(source == "*" || source == "Internet" || source == "0.0.0.0/0") {
+			from, to := parsePortRange(stringValue(r.Values, "destination_port_range"))
+			add("source_address_prefix", from, to, strings.ToLower(stringValue(r.Values, "protocol")), source)
+		}
+	}
+
+	return findings
+}
+
+// openCIDR returns the world-open CIDR of an AWS ingress rule, if any
+func openCIDR(rule map[string]interface{}) string {
+	for _, cidr := range listValue(rule, "cidr_blocks") {
+		if cidr == "0.0.0.0/0" {
+			return "0.0.0.0/0"
+		}
+	}
+	for _, cidr := range listValue(rule, "ipv6_cidr_blocks") {
+		if cidr == "::/0" {
+			return "::/0"
+		}
+	}
+	return ""
+}
+
+// cidrAttribute is the AWS rule attribute holding a CIDR returned by openCIDR
+func cidrAttribute(cidr string) string {
+	if cidr == "::/0" {
+		return "ipv6_cidr_blocks"
+	}
+	return "cidr_blocks"
+}
+
+// ingressSeverity rates an internet-facing port range. Web ports are expected to be
+// public, while management and database ports or whole ranges are not.
+func ingressSeverity(fromPort, toPort int, protocol string) (string, string) {
+	if protocol == "-1" || protocol == "all" || protocol == "*" || (fromPort <= 0 && toPort >= 65535) {
+		return "high", "all ports"
+	}
+
+	var exposed []string
+	for port := fromPort; port <= toPort && port-fromPort < 65536; port++ {
+		if name, ok := sensitivePorts[port]; ok {
+			exposed = append(exposed, fmt.Sprintf("%d (%s)", port, name))
+		}
+	}
+	portRange := strconv.Itoa(fromPort)
+	if toPort != fromPort {
+		portRange = fmt.Sprintf("%d-%d", fromPort, toPort)
+	}
+
+	if len(exposed) > 0 {
+		if fromPort == toPort {
+			return "high", "port " + exposed[0]
+		}
+		return "high", fmt.Sprintf("ports %s including %s", portRange, strings.Join(exposed, ", "))
+	}
+	if fromPort == toPort && (fromPort == 80 || fromPort == 443) {
+		return "low", "port " + portRange
+	}
+	if fromPort == toPort {
+		return "medium", "port " + portRange
+	}
+	return "medium", "ports " + portRange
+}
+
+func parsePortRange(s string) (int, int) {
+	if s == "*" || s == "" {
+		return 0, 65535
+	}
+	from, to, ok := strings.Cut(s, "-")
+	fromPort, _ := strconv.Atoi(from)
+	if !ok {
+		return fromPort, fromPort
+	}
+	toPort, _ := strconv.Atoi(to)
+	return fromPort, toPort
+}
+
+func checkEncryption(r Resource) []Finding {
+	var findings []Finding
+	add := func(attribute, message string) {
+		findings = append(findings, Finding{Severity: "high", Rule: "unencrypted-storage", Address: r.Address, Message: message, attribute: attribute})
+	}
+
+	switch r.Type {
+	case "aws_ebs_volume":
+		if isFalseOrUnset(r, "encrypted") {
+			add("encrypted", "EBS volume is not encrypted")
+		}
+	case "aws_instance":
+		for _, key := range []string{"root_block_device", "ebs_block_device"} {
+			for i, device := range listValue(r.Values, key) {
+				device, _ := device.(map[string]interface{})
+				if encrypted, ok := boolValue(device, "encrypted"); ok && !encrypted {
+					add(fmt.Sprintf("%s.%d.encrypted", key, i), fmt.Sprintf("%s is not encrypted", key))
+				}
+			}
+		}
+	case "aws_db_instance", "aws_rds_cluster", "aws_docdb_cluster", "aws_neptune_cluster":
+		if isFalseOrUnset(r, "storage_encrypted") {
+			add("storage_encrypted", "database storage is not encrypted")
+		}
+	case "aws_efs_file_system":
+		if isFalseOrUnset(r, "encrypted") {
+			add("encrypted", "EFS file system is not encrypted")
+		}
+	case "aws_elasticache_replication_group":
+		if isFalseOrUnset(r, "at_rest_encryption_enabled") {
+			add("at_rest_encryption_enabled", "ElastiCache data is not encrypted at rest")
+		}
+	}
+
+	return findings
+}
+
+// checkTags flags taggable resources without any tags. tags_all includes provider
+// default_tags, so it is preferred when present.
+func checkTags(r Resource) []Finding {
+	key := "tags_all"
+	if _, ok := r.Values[key]; !ok {
+		key = "tags"
+	}
+	if _, ok := r.Values[key]; !ok {
+		if _, ok := r.Values["labels"]; !ok || !strings.HasPrefix(r.Type, "google_") {
+			return nil
+		}
+		key = "labels"
+	}
+
+	// Tags built from expressions that can't be resolved statically are assumed to be set
+	switch tags := r.Values[key].(type) {
+	case map[string]interface{}:
+		if len(tags) > 0 {
+			return nil
+		}
+	case nil:
+	default:
+		return nil
+	}
+
+	return []Finding{{
+		Severity:  "low",
+		Rule:      "untagged-resource",
+		Address:   r.Address,
+		Message:   "resource has no tags, so ownership and cost allocation are unknown",
+		attribute: key,
+	}}
+}
+
+func checkInstanceSize(r Resource) []Finding {
+	var attribute, size string
+	var vcpus int
+
+	switch r.Type {
+	case "aws_instance", "aws_launch_template", "aws_db_instance", "aws_rds_cluster_instance":
+		attribute = "instance_type"
+		size = stringValue(r.Values, attribute)
+		if size == "" {
+			attribute = "instance_class"
+			size = stringValue(r.Values, attribute)
+		}
+		if strings.HasSuffix(size, ".metal") {
+			vcpus = oversizedVCPUs
+		} else if m := awsSizePattern.FindStringSubmatch(size); m != nil {
+			// An N-xlarge instance has 4*N vCPUs
+			n, _ := strconv.Atoi(m[1])
+			vcpus = 4 * n
+		}
+	case "google_compute_instance", "google_compute_instance_template":
+		attribute = "machine_type"
+		size = stringValue(r.Values, attribute)
+		if m := gcpVCPUPattern.FindStringSubmatch(size); m != nil {
+			vcpus, _ = strconv.Atoi(m[1])
+		}
+	case "azurerm_linux_virtual_machine", "azurerm_windows_virtual_machine", "azurerm_virtual_machine":
+		attribute = "size"
+		size = stringValue(r.Values, attribute)
+		if size == "" {
+			attribute = "vm_size"
+			size = stringValue(r.Values, attribute)
+		}
+		if m := azureVCPUPattern.FindStringSubmatch(size); m != nil {
+			vcpus, _ = strconv.Atoi(m[1])
+		}
+	default:
+		return nil
+	}
+
+	if vcpus < oversizedVCPUs {
+		return nil
+	}
+
+	return []Finding{{
+		Severity:  "medium",
+		Rule:      "oversized-instance",
+		Address:   r.Address,
+		Message:   fmt.Sprintf("%s provides %d+ vCPUs; confirm utilization justifies this size", size, vcpus),
+		attribute: attribute,
+	}}
+}
+
+func isDeleteOnly(actions []string) bool {
+	return len(actions) == 1 && actions[0] == "delete"
+}
+
+// isFalseOrUnset reports whether a boolean attribute is explicitly false or missing (and
+// so takes the provider's default of false). Unresolved expressions and values a plan
+// only knows after apply are not flagged.
+func isFalseOrUnset(r Resource, key string) bool {
+	if unknown, _ := r.Unknown[key].(bool); unknown {
+		return false
+	}
+	switch v := r.Values[key].(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	}
+	return false
+}
+
+func stringValue(values map[string]interface{}, key string) string {
+	s, _ := values[key].(string)
+	return s
+}
+
+func boolValue(values map[string]interface{}, key string) (bool, bool) {
+	b, ok := values[key].(bool)
+	return b, ok
+}
+
+func intValue(values map[string]interface{}, key string) int {
+	switch v := values[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+func listValue(values map[string]interface{}, key string) []interface{} {
+	list, _ := values[key].([]interface{})
+	return list
+}
+
+// CheckConfig runs the resource checks against source files plus checks that only
+// apply to source, such as undocumented variables and unpinned module versions
+func CheckConfig(cfg *Config) []Finding {
+	findings := Check(cfg.Resources)
+
+	for _, v := range cfg.Variables {
+		if v.Description == "" {
+			findings = append(findings, Finding{
+				Severity: "low",
+				Rule:     "variable-description",
+				Address:  "var." + v.Name,
+				Message:  "variable has no description",
+				Location: v.Location,
+			})
+		}
+		if v.Type == "" {
+			findings = append(findings, Finding{
+				Severity: "low",
+				Rule:     "variable-type",
+				Address:  "var." + v.Name,
+				Message:  "variable has no type constraint",
+				Location: v.Location,
+			})
+		}
+	}
+
+	for _, m := range cfg.Modules {
+		if message := moduleSourceIssue(m); message != "" {
+			findings = append(findings, Finding{
+				Severity: "medium",
+				Rule:     "module-version-pin",
+				Address:  "module." + m.Name,
+				Message:  message,
+				Location: m.Location,
+			})
+		}
+	}
+
+	sortFindings(findings)
+	return findings
+}
+
+// moduleSourceIssue reports a module source that isn't pinned to a specific version
+func moduleSourceIssue(m ModuleCall) string {
+	source := m.Source
+	switch {
+	case source == "" || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../"):
+		// Local modules are versioned with the calling code
+		return ""
+	case strings.HasPrefix(source, "git::") || strings.HasPrefix(source, "github.com/") ||
+		strings.HasPrefix(source, "bitbucket.org/") || strings.Contains(source, ".git"):
+		if !strings.Contains(source, "ref=") {
+			return fmt.Sprintf("module source %q is not pinned to a ref", source)
+		}
+	case strings.Count(source, "/") == 2 && !strings.Contains(source, "://"):
+		// Registry modules (namespace/name/provider) are pinned with the version argument
+		if m.Version == "" {
+			return fmt.Sprintf("registry module %q has no version constraint", source)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSON(t *testing.T) {
+	plan := `{
+		"format_version": "1.2",
+		"terraform_version": "1.9.0",
+		"planned_values": {"root_module": {
+			"resources": [
+				{"address": "aws_ebs_volume.data", "mode": "managed", "type": "aws_ebs_volume", "name": "data", "values": {"size": 10}}
+			],
+			"child_modules": [{"address": "module.db", "resources": [
+				{"address": "module.db.aws_db_instance.main", "mode": "managed", "type": "aws_db_instance", "name": "main", "values": {"storage_encrypted": true}}
+			]}]
+		}},
+		"resource_changes": [
+			{"address": "aws_ebs_volume.data", "change": {"actions": ["create"], "after_unknown": {"encrypted": true, "id": true}}},
+			{"address": "module.db.aws_db_instance.main", "change": {"actions": ["update"]}},
+			{"address": "aws_s3_bucket.old", "mode": "managed", "type": "aws_s3_bucket", "name": "old", "change": {"actions": ["delete"]}}
+		]
+	}`
+
+	doc, err := ParseJSON([]byte(plan))
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+	if doc.Kind != "plan" || doc.TerraformVersion != "1.9.0" {
+		t.Errorf("ParseJSON() kind, version = %q, %q, want plan, 1.9.0", doc.Kind, doc.TerraformVersion)
+	}
+
+	want := []Resource{
+		{Address: "aws_ebs_volume.data", Mode: "managed", Type: "aws_ebs_volume", Name: "data",
+			Values: map[string]interface{}{"size": float64(10)}, Actions: []string{"create"},
+			Unknown: map[string]interface{}{"encrypted": true, "id": true}},
+		{Address: "aws_s3_bucket.old", Mode: "managed", Type: "aws_s3_bucket", Name: "old", Actions: []string{"delete"}},
+		{Address: "module.db.aws_db_instance.main", Mode: "managed", Type: "aws_db_instance", Name: "main",
+			Values: map[string]interface{}{"storage_encrypted": true}, Actions: []string{"update"}},
+	}
+	if !reflect.DeepEqual(doc.Resources, want) {
+		t.Errorf("ParseJSON() resources = %+v, want %+v", doc.Resources, want)
+	}
+	if counts := doc.ActionCounts(); !reflect.DeepEqual(counts, map[string]int{"create": 1, "update": 1, "delete": 1}) {
+		t.Errorf("ActionCounts() = %v", counts)
+	}
+
+	if _, err := ParseJSON([]byte(`{"values": {}}`)); err == nil {
+		t.Error("ParseJSON() without format_version succeeded, want an error")
+	}
+}
+
+// rules lists the rule of each finding, in order
+func rules(findings []Finding) []string {
+	var list []string
+	for _, f := range findings {
+		list = append(list, f.Rule)
+	}
+	return list
+}
+
+func TestCheck(t *testing.T) {
+	tagged := map[string]interface{}{"team": "platform"}
+
+	tests := []struct {
+		name     string
+		resource Resource
+		want     []string
+	}{
+		{
+			name:     "public bucket acl",
+			resource: Resource{Type: "aws_s3_bucket", Values: map[string]interface{}{"acl": "public-read", "tags": tagged}},
+			want:     []string{"s3-public-access"},
+		},
+		{
+			name: "public access block disabled",
+			resource: Resource{Type: "aws_s3_bucket_public_access_block", Values: map[string]interface{}{
+				"block_public_acls": false, "block_public_policy": true}},
+			want: []string{"s3-public-access"},
+		},
+		{
+			name: "public bucket policy",
+			resource: Resource{Type: "aws_s3_bucket_policy", Values: map[string]interface{}{
+				"policy": `{"Statement": {"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject"}}`}},
+			want: []string{"s3-public-access"},
+		},
+		{
+			name: "conditional bucket policy",
+			resource: Resource{Type: "aws_s3_bucket_policy", Values: map[string]interface{}{
+				"policy": `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "*"}, "Condition": {"StringEquals": {}}}]}`}},
+		},
+		{
+			name: "ssh open to the world",
+			resource: Resource{Type: "aws_security_group", Values: map[string]interface{}{"tags": tagged, "ingress": []interface{}{
+				map[string]interface{}{"from_port": float64(22), "to_port": float64(22), "protocol": "tcp", "cidr_blocks": []interface{}{"0.0.0.0/0"}},
+				map[string]interface{}{"from_port": float64(5432), "to_port": float64(5432), "protocol": "tcp", "cidr_blocks": []interface{}{"10.0.0.0/8"}},
+			}}},
+			want: []string{"open-ingress"},
+		},
+		{
+			name: "gcp firewall egress ignored",
+			resource: Resource{Type: "google_compute_firewall", Values: map[string]interface{}{
+				"direction": "EGRESS", "source_ranges": []interface{}{"0.0.0.0/0"}}},
+		},
+		{
+			name:     "unencrypted volume",
+			resource: Resource{Type: "aws_ebs_volume", Values: map[string]interface{}{"encrypted": false, "tags": tagged}},
+			want:     []string{"unencrypted-storage"},
+		},
+		{
+			name:     "encryption unset",
+			resource: Resource{Type: "aws_efs_file_system", Values: map[string]interface{}{"tags": tagged}},
+			want:     []string{"unencrypted-storage"},
+		},
+		{
+			name: "encryption known after apply",
+			resource: Resource{Type: "aws_db_instance", Values: map[string]interface{}{"tags": tagged},
+				Unknown: map[string]interface{}{"storage_encrypted": true}},
+		},
+		{
+			name:     "encryption from an unresolved expression",
+			resource: Resource{Type: "aws_ebs_volume", Values: map[string]interface{}{"encrypted": "${var.encrypted}", "tags": tagged}},
+		},
+		{
+			name: "unencrypted instance root device",
+			resource: Resource{Type: "aws_instance", Values: map[string]interface{}{"instance_type": "t3.micro", "tags": tagged,
+				"root_block_device": []interface{}{map[string]interface{}{"encrypted": false}}}},
+			want: []string{"unencrypted-storage"},
+		},
+		{
+			name:     "empty tags",
+			resource: Resource{Type: "aws_sqs_queue", Values: map[string]interface{}{"tags": map[string]interface{}{}}},
+			want:     []string{"untagged-resource"},
+		},
+		{
+			name: "provider default tags",
+			resource: Resource{Type: "aws_sqs_queue", Values: map[string]interface{}{
+				"tags": map[string]interface{}{}, "tags_all": tagged}},
+		},
+		{
+			name:     "oversized aws instance",
+			resource: Resource{Type: "aws_instance", Values: map[string]interface{}{"instance_type": "m5.16xlarge", "tags": tagged}},
+			want:     []string{"oversized-instance"},
+		},
+		{
+			name:     "azure vm under the threshold",
+			resource: Resource{Type: "azurerm_linux_virtual_machine", Values: map[string]interface{}{"size": "Standard_D16s_v5", "tags": tagged}},
+		},
+		{
+			name:     "data sources skipped",
+			resource: Resource{Mode: "data", Type: "aws_ebs_volume", Values: map[string]interface{}{}},
+		},
+		{
+			name:     "deletions skipped",
+			resource: Resource{Type: "aws_ebs_volume", Values: map[string]interface{}{}, Actions: []string{"delete"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules(Check([]Resource{tt.resource})); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngressSeverity(t *testing.T) {
+	tests := []struct {
+		from, to int
+		protocol string
+		severity string
+		exposure string
+	}{
+		{0, 65535, "tcp", "high", "all ports"},
+		{0, 0, "-1", "high", "all ports"},
+		{3389, 3389, "tcp", "high", "port 3389 (RDP)"},
+		{6000, 6400, "tcp", "high", "ports 6000-6400 including 6379 (Redis)"},
+		{443, 443, "tcp", "low", "port 443"},
+		{8080, 8080, "tcp", "medium", "port 8080"},
+		{8000, 8100, "tcp", "medium", "ports 8000-8100"},
+	}
+	for _, tt := range tests {
+		severity, exposure := ingressSeverity(tt.from, tt.to, tt.protocol)
+		if severity != tt.severity || exposure != tt.exposure {
+			t.Errorf("ingressSeverity(%d, %d, %q) = %q, %q, want %q, %q",
+				tt.from, tt.to, tt.protocol, severity, exposure, tt.severity, tt.exposure)
+		}
+	}
+}
+
+func TestModuleSourceIssue(t *testing.T) {
+	tests := []struct {
+		module  ModuleCall
+		flagged bool
+	}{
+		{ModuleCall{Source: "./modules/vpc"}, false},
+		{ModuleCall{Source: "terraform-aws-modules/vpc/aws"}, true},
+		{ModuleCall{Source: "terraform-aws-modules/vpc/aws", Version: "~> 5.0"}, false},
+		{ModuleCall{Source: "git::https://example.com/modules.git"}, true},
+		{ModuleCall{Source: "git::https://example.com/modules.git?ref=v1.2.0"}, false},
+		{ModuleCall{Source: "github.com/org/module"}, true},
+	}
+	for _, tt := range tests {
+		if got := moduleSourceIssue(tt.module); (got != "") != tt.flagged {
+			t.Errorf("moduleSourceIssue(%+v) = %q, want flagged %v", tt.module, got, tt.flagged)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// taggableTypes are common resource types that support tags. When one of these is
+// declared in source without a tags argument, it is reported as untagged.
+var taggableTypes = map[string]bool{
+	"aws_instance": true, "aws_s3_bucket": true, "aws_ebs_volume": true, "aws_db_instance": true,
+	"aws_rds_cluster": true, "aws_security_group": true, "aws_vpc": true, "aws_subnet": true,
+	"aws_lb": true, "aws_eks_cluster": true, "aws_lambda_function": true, "aws_efs_file_system": true,
+	"aws_dynamodb_table": true, "aws_launch_template": true, "aws_autoscaling_group": true,
+	"azurerm_resource_group": true, "azurerm_linux_virtual_machine": true, "azurerm_windows_virtual_machine": true,
+	"azurerm_storage_account": true, "azurerm_kubernetes_cluster": true, "azurerm_virtual_network": true,
+	"google_compute_instance": true, "google_storage_bucket": true, "google_container_cluster": true,
+	"google_compute_disk": true, "google_sql_database_instance": true,
+}
+
+// Config is the static view of a Terraform module parsed from .tf/.hcl source files
+type Config struct {
+	Files     []string     `json:"files"`
+	Resources []Resource   `json:"resources"`
+	Modules   []ModuleCall `json:"modules,omitempty"`
+	Variables []Variable   `json:"variables,omitempty"`
+	Outputs   []string     `json:"outputs,omitempty"`
+	Providers []string     `json:"providers,omitempty"`
+	Edges     []Edge       `json:"edges,omitempty"`
+}
+
+// ModuleCall is a module block
+type ModuleCall struct {
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Version  string `json:"version,omitempty"`
+	Location string `json:"location"`
+}
+
+// Variable is an input variable block
+type Variable struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	HasDefault  bool   `json:"hasDefault"`
+	Sensitive   bool   `json:"sensitive,omitempty"`
+	Location    string `json:"location"`
+}
+
+// Edge is a reference from one configuration object to another, e.g.
+// aws_instance.web -> aws_security_group.web or module.vpc -> var.cidr
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// sourceFile is a parsed file along with its raw bytes, used to recover the
+// source text of expressions that can't be evaluated statically
+type sourceFile struct {
+	path  string
+	body  *hclsyntax.Body
+	bytes []byte
+}
+
+// ParseSource parses Terraform source files. Each path may be a .tf/.hcl file or a
+// directory, in which case the .tf and .hcl files directly inside it are parsed.
+func ParseSource(paths ...string) (*Config, error) {
+	files, err := expandSourcePaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .tf or .hcl files found")
+	}
+
+	parser := hclparse.NewParser()
+	var parsed []sourceFile
+	for _, path := range files {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, diags.Error())
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse %s: unexpected body type", path)
+		}
+		parsed = append(parsed, sourceFile{path: path, body: body, bytes: file.Bytes})
+	}
+
+	cfg := &Config{Files: files}
+	ctx := buildEvalContext(parsed)
+	defaultTags := hasDefaultTags(parsed)
+	edges := make(map[Edge]bool)
+
+	for _, file := range parsed {
+		for _, block := range file.body.Blocks {
+			location := fmt.Sprintf("%s:%d", file.path, block.DefRange().Start.Line)
+
+			switch block.Type {
+			case "resource", "data":
+				if len(block.Labels) != 2 {
+					continue
+				}
+				r := Resource{
+					Address:  block.Labels[0] + "." + block.Labels[1],
+					Mode:     "managed",
+					Type:     block.Labels[0],
+					Name:     block.Labels[1],
+					Values:   bodyValues(block.Body, ctx, file.bytes),
+					Location: location,
+
+					attributeLocations: make(map[string]string),
+				}
+				recordLocations(r.attributeLocations, "", file.path, block.Body)
+				if block.Type == "data" {
+					r.Address = "data." + r.Address
+					r.Mode = "data"
+				}
+				// provider = aws.west selects an aliased configuration of the aws provider
+				if p, ok := r.Values["provider"].(string); ok {
+					r.Provider, _, _ = strings.Cut(strings.Trim(p, "${}"), ".")
+				} else {
+					r.Provider, _, _ = strings.Cut(r.Type, "_")
+				}
+				if _, ok := r.Values["tags"]; !ok && taggableTypes[r.Type] && !defaultTags[r.Provider] {
+					r.Values["tags"] = nil
+				}
+				cfg.Resources = append(cfg.Resources, r)
+				addReferences(edges, r.Address, block.Body)
+
+			case "module":
+				if len(block.Labels) != 1 {
+					continue
+				}
+				values := bodyValues(block.Body, ctx, file.bytes)
+				source, _ := values["source"].(string)
+				version, _ := values["version"].(string)
+				cfg.Modules = append(cfg.Modules, ModuleCall{
+					Name:     block.Labels[0],
+					Source:   source,
+					Version:  version,
+					Location: location,
+				})
+				addReferences(edges, "module."+block.Labels[0], block.Body)
+
+			case "variable":
+				if len(block.Labels) != 1 {
+					continue
+				}
+				v := Variable{Name: block.Labels[0], Location: location}
+				if attr, ok := block.Body.Attributes["type"]; ok {
+					v.Type = string(attr.Expr.Range().SliceBytes(file.bytes))
+				}
+				if attr, ok := block.Body.Attributes["description"]; ok {
+					if value, diags := attr.Expr.Value(nil); !diags.HasErrors() && value.Type() == cty.String && value.IsKnown() && !value.IsNull() {
+						v.Description = value.AsString()
+					}
+				}
+				_, v.HasDefault = block.Body.Attributes["default"]
+				if attr, ok := block.Body.Attributes["sensitive"]; ok {
+					value, diags := attr.Expr.Value(nil)
+					v.Sensitive = !diags.HasErrors() && value.Type() == cty.Bool && value.True()
+				}
+				cfg.Variables = append(cfg.Variables, v)
+
+			case "output":
+				if len(block.Labels) != 1 {
+					continue
+				}
+				cfg.Outputs = append(cfg.Outputs, block.Labels[0])
+				addReferences(edges, "output."+block.Labels[0], block.Body)
+
+			case "provider":
+				if len(block.Labels) == 1 {
+					cfg.Providers = append(cfg.Providers, block.Labels[0])
+				}
+			}
+		}
+	}
+
+	for edge := range edges {
+		cfg.Edges = append(cfg.Edges, edge)
+	}
+	sort.Slice(cfg.Edges, func(i, j int) bool {
+		if cfg.Edges[i].From != cfg.Edges[j].From {
+			return cfg.Edges[i].From < cfg.Edges[j].From
+		}
+		return cfg.Edges[i].To < cfg.Edges[j].To
+	})
+
+	return cfg, nil
+}
+
+func expandSourcePaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Terraform source: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		for _, pattern := range []string{"*.tf", "*.hcl"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// buildEvalContext exposes variable defaults and locals so that attributes such as
+// `encrypted = var.encrypt_volumes` can be resolved without running Terraform
+func buildEvalContext(files []sourceFile) *hcl.EvalContext {
+	vars := make(map[string]cty.Value)
+	for _, file := range files {
+		for _, block := range file.body.Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 {
+				continue
+			}
+			if attr, ok := block.Body.Attributes["default"]; ok {
+				if value, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+					vars[block.Labels[0]] = value
+				}
+			}
+		}
+	}
+
+	ctx := &hcl.EvalContext{Variables: map[string]cty.Value{"var": cty.ObjectVal(vars)}}
+
+	// Locals may refer to other locals, so resolve them over a few passes
+	locals := make(map[string]cty.Value)
+	for pass := 0; pass < 3; pass++ {
+		ctx.Variables["local"] = cty.ObjectVal(locals)
+		for _, file := range files {
+			for _, block := range file.body.Blocks {
+				if block.Type != "locals" {
+					continue
+				}
+				for name, attr := range block.Body.Attributes {
+					if _, done := locals[name]; done {
+						continue
+					}
+					if value, diags := attr.Expr.Value(ctx); !diags.HasErrors() && value.IsWhollyKnown() {
+						locals[name] = value
+					}
+				}
+			}
+		}
+	}
+	ctx.Variables["local"] = cty.ObjectVal(locals)
+
+	return ctx
+}
+
+// hasDefaultTags reports which providers configure default_tags, which apply to every resource
+func hasDefaultTags(files []sourceFile) map[string]bool {
+	providers := make(map[string]bool)
+	for _, file := range files {
+		for _, block := range file.body.Blocks {
+			if block.Type != "provider" || len(block.Labels) != 1 {
+				continue
+			}
+			for _, nested := range block.Body.Blocks {
+				if nested.Type == "default_tags" {
+					providers[block.Labels[0]] = true
+				}
+			}
+		}
+	}
+	return providers
+}
+
+// bodyValues converts a block body into the same shape as `terraform show -json`
+// values: attributes become plain values and nested blocks become lists of objects.
+// Expressions that can't be evaluated statically are kept as "${<source>}" strings.
+func bodyValues(body *hclsyntax.Body, ctx *hcl.EvalContext, src []byte) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	for name, attr := range body.Attributes {
+		value, diags := attr.Expr.Value(ctx)
+		if diags.HasErrors() || !value.IsWhollyKnown() {
+			values[name] = "${" + string(attr.Expr.Range().SliceBytes(src)) + "}"
+			continue
+		}
+		values[name] = ctyToGo(value)
+	}
+
+	for _, block := range body.Blocks {
+		// dynamic blocks are generated at plan time and can't be expanded statically
+		if block.Type == "dynamic" || block.Type == "lifecycle" {
+			continue
+		}
+		list, _ := values[block.Type].([]interface{})
+		values[block.Type] = append(list, bodyValues(block.Body, ctx, src))
+	}
+
+	return values
+}
+
+// recordLocations maps the attributes and nested blocks of body to their file:line,
+// using the paths bodyValues gives them, e.g. "root_block_device.0.encrypted"
+func recordLocations(locations map[string]string, prefix, path string, body *hclsyntax.Body) {
+	for name, attr := range body.Attributes {
+		locations[prefix+name] = fmt.Sprintf("%s:%d", path, attr.Range().Start.Line)
+	}
+
+	counts := make(map[string]int)
+	for _, block := range body.Blocks {
+		if block.Type == "dynamic" || block.Type == "lifecycle" {
+			continue
+		}
+		blockPath := fmt.Sprintf("%s%s.%d", prefix, block.Type, counts[block.Type])
+		counts[block.Type]++
+		locations[blockPath] = fmt.Sprintf("%s:%d", path, block.DefRange().Start.Line)
+		recordLocations(locations, blockPath+".", path, block.Body)
+	}
+}
+
+func ctyToGo(value cty.Value) interface{} {
+	if value.IsNull() {
+		return nil
+	}
+	data, err := ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// addReferences records an edge from the given address to everything referenced in body
+func addReferences(edges map[Edge]bool, from string, body *hclsyntax.Body) {
+	for _, attr := range body.Attributes {
+		for _, traversal := range attr.Expr.Variables() {
+			if to := referenceAddress(traversal); to != "" && to != from {
+				edges[Edge{From: from, To: to}] = true
+			}
+		}
+	}
+	for _, block := range body.Blocks {
+		addReferences(edges, from, block.Body)
+	}
+}
+
+// referenceAddress converts a traversal such as aws_vpc.main.id or module.vpc.vpc_id
+// into the address of the object it refers to
+func referenceAddress(traversal hcl.Traversal) string {
+	var parts []string
+	for _, step := range traversal {
+		// Stop at the first index step, e.g. aws_instance.web[0]
+		if root, ok := step.(hcl.TraverseRoot); ok {
+			parts = append(parts, root.Name)
+		} else if attr, ok := step.(hcl.TraverseAttr); ok {
+			parts = append(parts, attr.Name)
+		} else {
+			break
+		}
+	}
+
+	if len(parts) < 2 {
+		return ""
+	}
+	switch parts[0] {
+	case "count", "each", "self", "path", "terraform":
+		return ""
+	case "data":
+		if len(parts) < 3 {
+			return ""
+		}
+		return strings.Join(parts[:3], ".")
+	default:
+		// var.x, local.x, module.x and resource references all use two parts
+		return parts[0] + "." + parts[1]
+	}
+}
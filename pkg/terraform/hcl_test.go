@@ -0,0 +1,109 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckConfigLocations(t *testing.T) {
+	source := `variable "encrypt" {
+  default = false
+}
+
+resource "aws_ebs_volume" "data" {
+  size      = 100
+  encrypted = var.encrypt
+  tags      = { team = "platform" }
+}
+
+resource "aws_security_group" "web" {
+  tags = { team = "platform" }
+
+  ingress {
+    from_port   = 443
+    to_port     = 443
+    protocol    = "tcp"
+    cidr_blocks = ["10.0.0.0/8"]
+  }
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+resource "aws_efs_file_system" "shared" {
+}
+
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ParseSource(dir)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+
+	tests := []struct {
+		rule    string
+		address string
+		line    string
+	}{
+		// Findings point at the offending attribute or nested block when it is set
+		{"unencrypted-storage", "aws_ebs_volume.data", ":7"},
+		{"open-ingress", "aws_security_group.web", ":20"},
+		// and at the resource when the attribute is missing
+		{"unencrypted-storage", "aws_efs_file_system.shared", ":28"},
+		{"untagged-resource", "aws_efs_file_system.shared", ":28"},
+		{"module-version-pin", "module.vpc", ":31"},
+		{"variable-description", "var.encrypt", ":1"},
+	}
+
+	findings := CheckConfig(cfg)
+	for _, tt := range tests {
+		found := false
+		for _, f := range findings {
+			if f.Rule == tt.rule && f.Address == tt.address {
+				found = true
+				if want := path + tt.line; f.Location != want {
+					t.Errorf("%s finding for %s at %s, want %s", tt.rule, tt.address, f.Location, want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no %s finding for %s", tt.rule, tt.address)
+		}
+	}
+}
+
+func TestAttributeLocation(t *testing.T) {
+	r := Resource{
+		Location: "main.tf:10",
+		attributeLocations: map[string]string{
+			"instance_type":       "main.tf:11",
+			"root_block_device.0": "main.tf:14",
+		},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"instance_type", "main.tf:11"},
+		{"root_block_device.0.encrypted", "main.tf:14"},
+		{"ebs_block_device.0.encrypted", "main.tf:10"},
+		{"", "main.tf:10"},
+	}
+	for _, tt := range tests {
+		if got := r.attributeLocation(tt.path); got != tt.want {
+			t.Errorf("attributeLocation(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Resource is a single managed or data resource from Terraform state, a plan or source files
+type Resource struct {
+	Address  string                 `json:"address"`
+	Mode     string                 `json:"mode"` // "managed" or "data"
+	Type     string                 `json:"type"`
+	Name     string                 `json:"name"`
+	Provider string                 `json:"provider,omitempty"`
+	Values   map[string]interface{} `json:"values,omitempty"`
+	Actions  []string               `json:"actions,omitempty"`  // planned actions, e.g. ["create"] or ["delete", "create"]
+	Unknown  map[string]interface{} `json:"unknown,omitempty"`  // plan after_unknown: attributes only known after apply
+	Location string                 `json:"location,omitempty"` // file:line, only known for source files
+
+	// attributeLocations maps attribute paths such as "ingress.0.cidr_blocks" to the
+	// file:line setting them, for source files
+	attributeLocations map[string]string
+}
+
+// attributeLocation returns where an attribute path is set in source, falling back to
+// its enclosing block and then the resource itself
+func (r Resource) attributeLocation(path string) string {
+	for path != "" {
+		if location, ok := r.attributeLocations[path]; ok {
+			return location
+		}
+		i := strings.LastIndexByte(path, '.')
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return r.Location
+}
+
+// Document is a parsed `terraform show -json` plan or state
+type Document struct {
+	Kind             string     `json:"kind"` // "plan" or "state"
+	TerraformVersion string     `json:"terraformVersion"`
+	Resources        []Resource `json:"resources"`
+}
+
+type showModule struct {
+	Address      string         `json:"address"`
+	Resources    []showResource `json:"resources"`
+	ChildModules []showModule   `json:"child_modules"`
+}
+
+type showResource struct {
+	Address      string                 `json:"address"`
+	Mode         string                 `json:"mode"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	ProviderName string                 `json:"provider_name"`
+	Values       map[string]interface{} `json:"values"`
+}
+
+type showOutput struct {
+	FormatVersion    string `json:"format_version"`
+	TerraformVersion string `json:"terraform_version"`
+	Values           *struct {
+		RootModule showModule `json:"root_module"`
+	} `json:"values"`
+	PlannedValues *struct {
+		RootModule showModule `json:"root_module"`
+	} `json:"planned_values"`
+	ResourceChanges []struct {
+		Address      string `json:"address"`
+		Mode         string `json:"mode"`
+		Type         string `json:"type"`
+		Name         string `json:"name"`
+		ProviderName string `json:"provider_name"`
+		Change       struct {
+			Actions      []string               `json:"actions"`
+			AfterUnknown map[string]interface{} `json:"after_unknown"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// LoadJSON reads the output of `terraform show -json` for either a saved plan or the current state
+func LoadJSON(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Terraform JSON: %v", err)
+	}
+	return ParseJSON(data)
+}
+
+// ParseJSON parses the output of `terraform show -json`
+func ParseJSON(data []byte) (*Document, error) {
+	var out showOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform JSON: %v", err)
+	}
+	if out.FormatVersion == "" {
+		return nil, fmt.Errorf("not a `terraform show -json` document (missing format_version)")
+	}
+
+	doc := &Document{TerraformVersion: out.TerraformVersion}
+
+	var root showModule
+	switch {
+	case out.PlannedValues != nil:
+		doc.Kind = "plan"
+		root = out.PlannedValues.RootModule
+	case out.Values != nil:
+		doc.Kind = "state"
+		root = out.Values.RootModule
+	default:
+		// An empty state has no values at all
+		doc.Kind = "state"
+	}
+
+	actions := make(map[string][]string)
+	unknown := make(map[string]map[string]interface{})
+	for _, change := range out.ResourceChanges {
+		actions[change.Address] = change.Change.Actions
+		unknown[change.Address] = change.Change.AfterUnknown
+	}
+
+	doc.Resources = flattenModule(root, actions, unknown)
+
+	// Resources being destroyed don't appear in planned_values, but are still worth reporting
+	known := make(map[string]bool)
+	for _, r := range doc.Resources {
+		known[r.Address] = true
+	}
+	for _, change := range out.ResourceChanges {
+		if !known[change.Address] && containsAction(change.Change.Actions, "delete") {
+			doc.Resources = append(doc.Resources, Resource{
+				Address:  change.Address,
+				Mode:     change.Mode,
+				Type:     change.Type,
+				Name:     change.Name,
+				Provider: change.ProviderName,
+				Actions:  change.Change.Actions,
+			})
+		}
+	}
+
+	sort.Slice(doc.Resources, func(i, j int) bool {
+		return doc.Resources[i].Address < doc.Resources[j].Address
+	})
+
+	return doc, nil
+}
+
+func flattenModule(module showModule, actions map[string][]string, unknown map[string]map[string]interface{}) []Resource {
+	var resources []Resource
+	for _, r := range module.Resources {
+		resources = append(resources, Resource{
+			Address:  r.Address,
+			Mode:     r.Mode,
+			Type:     r.Type,
+			Name:     r.Name,
+			Provider: r.ProviderName,
+			Values:   r.Values,
+			Actions:  actions[r.Address],
+			Unknown:  unknown[r.Address],
+		})
+	}
+	for _, child := range module.ChildModules {
+		resources = append(resources, flattenModule(child, actions, unknown)...)
+	}
+	return resources
+}
+
+// ActionCounts tallies planned actions across all resources, e.g. {"create": 3, "update": 1}
+func (d *Document) ActionCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, r := range d.Resources {
+		for _, action := range r.Actions {
+			if action != "no-op" && action != "read" {
+				counts[action]++
+			}
+		}
+	}
+	return counts
+}
+
+func containsAction(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
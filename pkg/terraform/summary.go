@@ -0,0 +1,70 @@
+package terraform
+
+import "sort"
+
+// maxSummaryAddresses caps how many resource addresses are listed in a summary
+const maxSummaryAddresses = 200
+
+// Summarize builds a compact, model-friendly description of the resources and findings
+func Summarize(resources []Resource, findings []Finding) map[string]interface{} {
+	typeCounts := make(map[string]int)
+	var addresses []string
+	for _, r := range resources {
+		if r.Type != "" {
+			typeCounts[r.Type]++
+		}
+		addresses = append(addresses, r.Address)
+	}
+	sort.Strings(addresses)
+
+	summary := map[string]interface{}{
+		"resourceCount": len(resources),
+		"resourceTypes": typeCounts,
+		"findings":      findings,
+	}
+	if len(addresses) > maxSummaryAddresses {
+		summary["addresses"] = addresses[:maxSummaryAddresses]
+		summary["addressesTruncated"] = len(addresses) - maxSummaryAddresses
+	} else {
+		summary["addresses"] = addresses
+	}
+
+	return summary
+}
+
+// maxSummaryEdges caps how many reference edges are included in a source summary
+const maxSummaryEdges = 300
+
+// SummarizeConfig extends Summarize with the module structure of source files: module
+// calls, variables, outputs and the reference graph between objects
+func SummarizeConfig(cfg *Config, findings []Finding) map[string]interface{} {
+	summary := Summarize(cfg.Resources, findings)
+	summary["files"] = cfg.Files
+	summary["providers"] = cfg.Providers
+	summary["modules"] = cfg.Modules
+	summary["outputs"] = cfg.Outputs
+
+	// Variable locations are already attached to findings, so only the shape is kept
+	variables := make([]map[string]interface{}, 0, len(cfg.Variables))
+	for _, v := range cfg.Variables {
+		variables = append(variables, map[string]interface{}{
+			"name":       v.Name,
+			"type":       v.Type,
+			"hasDefault": v.HasDefault,
+			"sensitive":  v.Sensitive,
+		})
+	}
+	summary["variables"] = variables
+
+	edges := make([]string, 0, len(cfg.Edges))
+	for _, e := range cfg.Edges {
+		edges = append(edges, e.From+" -> "+e.To)
+	}
+	if len(edges) > maxSummaryEdges {
+		summary["referencesTruncated"] = len(edges) - maxSummaryEdges
+		edges = edges[:maxSummaryEdges]
+	}
+	summary["references"] = edges
+
+	return summary
+}